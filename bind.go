@@ -1,131 +1,915 @@
 package hr
 
 import (
+	"encoding"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
+// maxBindIndex limits indexes of keys like items[0].sku, so that a
+// request can not make us allocate huge slices.
+const maxBindIndex = 1000
+
+var typeType = reflect.TypeOf((*Type)(nil)).Elem()
+
+// bind binds values to the fields of the struct v points to. Fields are
+// bound if they are tagged with tag, whose value is the key of the field
+// in values, and a key ending with '?' is optional. Nested structs are
+// bound with dotted keys, so a field tagged `form:"city"` in a struct
+// field tagged `form:"address"` is bound from "address.city". Keys in
+// the bracketed form are understood as well:
+//
+//	address[city]  -> address.city
+//	items[0][sku]  -> items.0.sku, the sku of the first element of items
+//	items[0].sku   -> items.0.sku
+//	tags[]         -> tags
+//	meta[key]      -> meta.key, the value keyed by "key" in the map meta
+//
+// Fields of embedded structs that are not tagged are bound as if they
+// were fields of the outer struct.
+//
+// Fields of types implementing Type or encoding.TextUnmarshaler, such as
+// time.Time and netip.Addr, are set from strings by the interfaces. Time
+// fields tagged with `layout`, like `layout:"2006-01-02"`, are parsed in
+// the layout rather than RFC 3339.
+//
+// Slice fields of basic types are bound in the collection format given
+// by the `collection` tag, like `collection:"csv"`, see collectionFormats
+// for all of them. It defaults to multi, where every value of the key is
+// an element of the slice.
+//
+// A field tagged with `default` is set to the default value if its key
+// is missing or its value is empty. Values are normalized before they
+// are set by the modifiers given by the `mod` tag, such as
+// `mod:"trim,lower"`, see modifiers for all of them.
 func bind(v interface{}, values map[string][]string, tag string) error {
+	b := binder{tag: tag}
+	return b.bind(v, values)
+}
+
+// binder binds values to a struct, collecting fields failed to bind
+// rather than stopping at the first one.
+type binder struct {
+	values     map[string][]string
+	listed     map[string][]string // values of keys ending with [], see setValues.
+	tag        string
+	collection string // the default collection format.
+	n          int    // number of fields set.
+	errs       BindError
+
+	// bound holds the paths of fields bound if not nil, which is shared
+	// by binders binding several sources to a struct in the order of
+	// precedence, so that fields bound from a source are skipped by the
+	// rest. Paths are the names of fields from the struct dotted, like
+	// Paging.Page and Items.0.SKU, see fieldPath.
+	bound map[string]bool
+}
+
+func (b *binder) bind(v interface{}, values map[string][]string) error {
 	if v == nil {
 		return nil
 	}
 
-	typ := reflect.TypeOf(v).Elem()
-	val := reflect.ValueOf(v).Elem()
-
-	if typ.Kind() != reflect.Struct {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Pointer || val.Elem().Kind() != reflect.Struct {
 		return errors.New("binding element must be a struct")
 	}
 
-	for i := 0; i < typ.NumField(); i++ {
-		fieldTyp := typ.Field(i)
-		fieldVal := val.Field(i)
+	b.setValues(values)
+	p := planOf(val.Type().Elem(), b.tag)
+	if p.err != nil {
+		return InternalServerError("%v", p.err)
+	}
+	if err := b.bindStruct(val.Elem(), p, "", ""); err != nil {
+		return err
+	}
+	return b.errs.err()
+}
 
-		if fieldTyp.Anonymous {
-			if val.Kind() == reflect.Ptr {
-				fieldVal = fieldVal.Elem()
-			}
+// fail records that the field keyed by key failed to bind.
+func (b *binder) fail(key, msg string, err error) {
+	for _, f := range b.errs.Fields {
+		if f.Source == b.tag && f.Key == key {
+			return
 		}
-		if !fieldVal.CanSet() {
-			// ignore private fields.
+	}
+	b.errs.Fields = append(b.errs.Fields, FieldError{
+		Source:  b.tag,
+		Key:     key,
+		Message: msg,
+		err:     err,
+	})
+}
+
+// missing records that the field at path keyed by key is missing.
+func (b *binder) missing(key, path string) {
+	n := len(b.errs.Fields)
+	b.fail(key, "missing field", nil)
+	if len(b.errs.Fields) > n {
+		b.errs.Fields[n].path = path
+	}
+}
+
+// skip reports whether the field at path has been bound from a source
+// of higher precedence.
+func (b *binder) skip(path string) bool {
+	return b.bound != nil && b.bound[path]
+}
+
+// done records that the field at path is bound.
+func (b *binder) done(path string) {
+	if b.bound != nil {
+		b.bound[path] = true
+	}
+}
+
+// leaf returns the values of the leaf field at path keyed by key, which
+// are the elements in the collection format if list is true. It reports
+// false if there is nothing to set the field to.
+func (b *binder) leaf(key string, f *field, list bool, path string) ([]string, bool) {
+	vals, sep := b.values[key], ""
+	if list {
+		vals, sep = b.collect(f.coll, key)
+	}
+	if len(f.mods) > 0 {
+		vals = modify(vals, f.mods)
+	}
+	if f.hasDef && (len(vals) == 0 || len(vals) == 1 && len(vals[0]) == 0) {
+		vals = split([]string{f.def}, sep)
+	}
+	if len(vals) == 0 {
+		if !f.opt {
+			b.missing(key, path)
+		}
+		return nil, false
+	}
+	b.n++
+	return vals, true
+}
+
+// result records the result of setting the leaf field at path.
+func (b *binder) result(key, path string, err error) {
+	if err != nil {
+		b.fail(key, "invalid value", err)
+		return
+	}
+	b.done(path)
+}
+
+// fieldPath returns the path of the field or element name in the struct
+// or slice at path.
+func fieldPath(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+// bindStruct binds the struct val at path, whose fields are keyed with
+// prefix.
+func (b *binder) bindStruct(val reflect.Value, p *plan, prefix, path string) error {
+	for i := range p.fields {
+		f := &p.fields[i]
+		fieldVal := val
+		for _, j := range f.index {
+			fieldVal = fieldVal.Field(j)
+		}
+		if f.kind == embeddedField {
+			fpath := fieldPath(path, f.path)
+			if err := b.bindEmbedded(fieldVal, f.plan, prefix, fpath); err != nil {
+				return err
+			}
 			continue
 		}
+		key := f.key
+		if len(prefix) > 0 {
+			key = prefix + "." + key
+		}
+		if err := b.bindField(fieldVal, f, key, fieldPath(path, f.path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindEmbedded binds the fields of the struct an untagged embedded
+// pointer points to, which is allocated only if there is something to
+// bind to the struct.
+func (b *binder) bindEmbedded(val reflect.Value, p *plan, prefix, path string) error {
+	if !val.IsNil() {
+		return b.bindStruct(val.Elem(), p, prefix, path)
+	}
+	n := b.n
+	ptr := reflect.New(val.Type().Elem())
+	if err := b.bindStruct(ptr.Elem(), p, prefix, path); err != nil {
+		return err
+	}
+	if b.n > n {
+		val.Set(ptr)
+	}
+	return nil
+}
+
+// field describes a field to bind by its tags.
+type field struct {
+	key    string
+	opt    bool                  // whether the key ends with '?'.
+	def    string                // the default value given by the `default` tag.
+	hasDef bool                  // whether there is a `default` tag.
+	mods   []func(string) string // the modifiers given by the `mod` tag.
+	layout string                // the time layout given by the `layout` tag.
+	coll   string                // the collection format given by the `collection` tag.
+}
+
+// fieldKind is how a field is bound.
+type fieldKind uint8
+
+const (
+	leafField     fieldKind = iota // set from the values of its key.
+	structField                    // bound from keys nested in its key.
+	sliceField                     // bound from indexed keys.
+	mapField                       // bound from keys like meta.key.
+	embeddedField                  // an untagged embedded struct pointer.
+	badField                       // of an unsupported type.
+)
+
+// plan is how to bind a struct type, which is compiled once for every
+// type and tag so that binding does not look into tags and methods of
+// the fields again.
+type plan struct {
+	fields []fieldPlan
+	err    error // the first error in the tags of the fields, if any.
+}
+
+// fieldPlan is how to bind a field of a struct, or an element of a
+// slice.
+type fieldPlan struct {
+	field
+	index  []int        // index path of the field, through embedded structs.
+	path   string       // names of the fields of index dotted, see fieldPath.
+	kind   fieldKind    // how the field is bound.
+	typ    reflect.Type // type of the field, or the one it points to if deref.
+	deref  bool         // whether it is a pointer to a struct, slice or map.
+	list   bool         // whether it is a leaf slice in a collection format.
+	set    valuesSetter // sets leaves, or map values.
+	setKey setter       // sets map keys.
+	plan   *plan        // plan of the struct, or the embedded one.
+	elem   *fieldPlan   // plan of slice elements.
+}
+
+type planKey struct {
+	typ reflect.Type
+	tag string
+}
+
+var plans sync.Map // map[planKey]*plan
+
+// planOf returns the plan of binding values tagged with tag to the
+// struct type t.
+func planOf(t reflect.Type, tag string) *plan {
+	key := planKey{t, tag}
+	if p, ok := plans.Load(key); ok {
+		return p.(*plan)
+	}
+	p, _ := plans.LoadOrStore(key, compilePlan(t, tag, make(map[reflect.Type]*plan)))
+	return p.(*plan)
+}
+
+// bindingTags are the tags binding fields from requests, in the order of
+// precedence.
+var bindingTags = []string{"path", "query", "header", "cookie", "form"}
+
+// checkTags returns the first error in the tags of the struct type t,
+// compiling the plans of binding it beforehand. The names and parameters
+// of validation rules are left to be checked against the rules of the
+// router.
+func checkTags(t reflect.Type) error {
+	for _, tag := range bindingTags {
+		if p := planOf(t, tag); p.err != nil {
+			return p.err
+		}
+	}
+	return compileValidation(t, nil, make(map[reflect.Type]*validation)).err
+}
 
-		kind := fieldVal.Kind()
+// compilePlan compiles the plan of the struct type t. Plans being
+// compiled are kept in seen, so that recursive types refer to them
+// rather than being compiled endlessly. Errors in the tags of the fields
+// are kept in the plan rather than panicking, since plans are compiled
+// while serving requests.
+func compilePlan(t reflect.Type, tag string, seen map[reflect.Type]*plan) *plan {
+	if p, ok := seen[t]; ok {
+		return p
+	}
+	p := &plan{}
+	seen[t] = p
+	p.fields, p.err = appendFieldPlans(nil, t, tag, nil, "", seen)
+	return p
+}
+
+// appendFieldPlans appends the plans of the fields of the struct type t
+// to fs. It stops at the first error in the tags of the fields.
+func appendFieldPlans(fs []fieldPlan, t reflect.Type, tag string,
+	index []int, path string, seen map[reflect.Type]*plan) ([]fieldPlan, error) {
+	for i := 0; i < t.NumField(); i++ {
+		fieldTyp := t.Field(i)
 		key := fieldTyp.Tag.Get(tag)
+		idx := append(append([]int{}, index...), i)
+		fpath := fieldPath(path, fieldTyp.Name)
 
+		if fieldTyp.Anonymous && len(key) == 0 && !isType(fieldTyp.Type) {
+			typ := fieldTyp.Type
+			switch {
+			case typ.Kind() == reflect.Struct:
+				// fields of an embedded struct are settable even if the
+				// struct type itself is private.
+				var err error
+				fs, err = appendFieldPlans(fs, typ, tag, idx, fpath, seen)
+				if err != nil {
+					return fs, err
+				}
+			case typ.Kind() == reflect.Pointer && typ.Elem().Kind() == reflect.Struct &&
+				fieldTyp.IsExported():
+				p := compilePlan(typ.Elem(), tag, seen)
+				if p.err != nil {
+					return fs, p.err
+				}
+				fs = append(fs, fieldPlan{
+					index: idx,
+					path:  fpath,
+					kind:  embeddedField,
+					plan:  p,
+				})
+			}
+			continue
+		}
+		if !fieldTyp.IsExported() {
+			// ignore private fields.
+			continue
+		}
 		if len(key) == 0 {
 			// ignore fields untagged.
 			continue
 		}
 
-		opt := key[len(key)-1] == '?'
-		if opt {
-			key = key[:len(key)-1]
+		f := field{key: key}
+		if f.opt = key[len(key)-1] == '?'; f.opt {
+			f.key = key[:len(key)-1]
 		}
+		f.def, f.hasDef = fieldTyp.Tag.Lookup("default")
+		f.layout = fieldTyp.Tag.Get("layout")
+		if len(f.layout) > 0 && !layoutType(fieldTyp.Type) {
+			err := layoutError(fieldTyp.Type)
+			return fs, fmt.Errorf("hr: field %s of %s: %w", fieldTyp.Name, t, err)
+		}
+		if f.coll = fieldTyp.Tag.Get("collection"); len(f.coll) > 0 {
+			if err := checkCollection(f.coll); err != nil {
+				return fs, fmt.Errorf("hr: field %s of %s: %w", fieldTyp.Name, t, err)
+			}
+		}
+		if mod := fieldTyp.Tag.Get("mod"); len(mod) > 0 {
+			var err error
+			if f.mods, err = compileModifiers(strings.Split(mod, ",")); err != nil {
+				return fs, fmt.Errorf("hr: field %s of %s: %w", fieldTyp.Name, t, err)
+			}
+		}
+		fp, err := compileField(fieldTyp.Type, f, tag, seen)
+		if err != nil {
+			return fs, err
+		}
+		fp.index, fp.path = idx, fpath
+		fs = append(fs, fp)
+	}
+	return fs, nil
+}
 
-		vals, ok := values[key]
-		if !ok {
-			if !opt {
-				return fmt.Errorf("missing field: %s", key)
+// compileField compiles the plan of a field of type t described by f,
+// failing with the errors of the plans of nested structs.
+func compileField(t reflect.Type, f field, tag string,
+	seen map[reflect.Type]*plan) (fieldPlan, error) {
+	fp := fieldPlan{field: f}
+	if t.Kind() == reflect.Pointer && !isLeaf(t) {
+		fp.deref, t = true, t.Elem()
+	}
+	fp.typ = t
+
+	switch {
+	case isLeaf(t):
+		fp.kind = leafField
+		fp.list = t.Kind() == reflect.Slice && !isType(t)
+		if layout := f.layout; len(layout) > 0 {
+			fp.set = func(v reflect.Value, vals []string) error {
+				return setTimes(v, vals, layout)
 			}
+		} else {
+			fp.set = newValuesSetter(t)
+		}
+	case t.Kind() == reflect.Struct:
+		fp.kind = structField
+		if fp.plan = compilePlan(t, tag, seen); fp.plan.err != nil {
+			return fp, fp.plan.err
+		}
+	case t.Kind() == reflect.Slice:
+		elem, err := compileField(t.Elem(), field{}, tag, seen)
+		if err != nil {
+			return fp, err
+		}
+		fp.kind = sliceField
+		fp.elem = &elem
+	case t.Kind() == reflect.Map && isLeaf(t.Elem()):
+		fp.kind = mapField
+		fp.setKey = newSetter(t.Key())
+		fp.set = newValuesSetter(t.Elem())
+	default:
+		fp.kind = badField
+	}
+	return fp, nil
+}
+
+// bindField binds the field val at path keyed by key.
+func (b *binder) bindField(val reflect.Value, f *fieldPlan, key, path string) error {
+	if b.skip(path) {
+		return nil
+	}
+	if f.deref {
+		if f.opt && !b.hasPrefix(key) {
+			return nil
+		}
+		if val.IsNil() {
+			val.Set(reflect.New(f.typ))
+		}
+		val = val.Elem()
+	}
+
+	switch f.kind {
+	case leafField:
+		if vals, ok := b.leaf(key, &f.field, f.list, path); ok {
+			b.result(key, path, f.set(val, vals))
+		}
+		return nil
+	case structField:
+		if f.opt && !b.hasPrefix(key) {
+			return nil
+		}
+		// required structs are bound even if nothing of them is given
+		// to report their missing fields.
+		return b.bindStruct(val, f.plan, key, path)
+	case sliceField:
+		return b.bindSlice(val, f, key, path)
+	case mapField:
+		return b.bindMap(val, f, key, path)
+	}
+	return fmt.Errorf("unsupported type %s of field: %s", f.typ, key)
+}
+
+// bindSlice binds a slice of structs from indexed keys.
+func (b *binder) bindSlice(val reflect.Value, f *fieldPlan, key, path string) error {
+	nerrs := len(b.errs.Fields)
+	indexes := b.indexes(key)
+	if len(indexes) == 0 {
+		// the field is not missing if there are bad indexes.
+		if !f.opt && len(b.errs.Fields) == nerrs {
+			b.missing(key, path)
+		}
+		return nil
+	}
+
+	n := indexes[len(indexes)-1] + 1
+	slice := reflect.MakeSlice(val.Type(), n, n)
+	for i := 0; i < n; i++ {
+		index := strconv.Itoa(i)
+		ekey, epath := key+"."+index, fieldPath(path, index)
+		if err := b.bindField(slice.Index(i), f.elem, ekey, epath); err != nil {
+			return err
+		}
+	}
+	val.Set(slice)
+	b.done(path)
+	return nil
+}
+
+// bindMap binds a map from keys like meta.key, where the rest of a key
+// after the prefix is the key in the map.
+func (b *binder) bindMap(val reflect.Value, f *fieldPlan, key, path string) error {
+	typ := val.Type()
+	prefix := key + "."
+	m := reflect.MakeMap(typ)
+	for k, vals := range b.values {
+		if !strings.HasPrefix(k, prefix) || len(vals) == 0 {
 			continue
 		}
+		mk := reflect.New(typ.Key()).Elem()
+		if err := f.setKey(k[len(prefix):], mk); err != nil {
+			b.fail(k, "invalid key", err)
+			continue
+		}
+		mv := reflect.New(typ.Elem()).Elem()
+		if err := f.set(mv, vals); err != nil {
+			b.fail(k, "invalid value", err)
+			continue
+		}
+		m.SetMapIndex(mk, mv)
+		b.n++
+	}
+	if m.Len() == 0 {
+		if !f.opt {
+			b.missing(key, path)
+		}
+		return nil
+	}
+	val.Set(m)
+	b.done(path)
+	return nil
+}
 
-		// we have to check if it is a self-defined type here.
-		// because types like net.IP (alias of []byte) is taken
-		// as of type slice by Go.
-		if iface, ok := fieldVal.Addr().Interface().(Type); ok {
-			if err := iface.Parse(vals[0]); err != nil {
-				return err
-			}
+// collectionFormats are the formats of the values of slice fields, which
+// follow the style and explode of OpenAPI parameters:
+//
+//	multi     ids=1&ids=2, form style exploded, also ids[]=1&ids[]=2
+//	csv       ids=1,2, form style not exploded
+//	ssv       ids=1%202, spaceDelimited style
+//	pipes     ids=1|2, pipeDelimited style
+//	brackets  ids[]=1&ids[]=2 only, ignoring ids=1
+//	indexed   ids[0]=1&ids[1]=2, or ids.0=1&ids.1=2, without gaps
+//
+// Values are split by the separators of csv, ssv and pipes even if the
+// key is repeated, and an empty value has no elements.
+var collectionFormats = map[string]string{
+	"multi":    "",
+	"csv":      ",",
+	"ssv":      " ",
+	"pipes":    "|",
+	"brackets": "",
+	"indexed":  "",
+}
+
+// CollectionFormat sets the collection format of slice fields that are
+// not tagged with `collection`, which is one of multi, csv, ssv, pipes,
+// brackets and indexed. It defaults to multi. See Ctx.Bind.
+func (r *Router) CollectionFormat(format string) {
+	if err := checkCollection(format); err != nil {
+		panic("hr: " + err.Error())
+	}
+	r.collection = format
+}
+
+// checkCollection fails if format is not a collection format.
+func checkCollection(format string) error {
+	if _, ok := collectionFormats[format]; !ok {
+		return fmt.Errorf("unknown collection format %q", format)
+	}
+	return nil
+}
+
+// collect returns the elements of the slice field keyed by key in the
+// collection format, or the default one if format is empty, and the
+// separator of the format if any. format is known, which is checked
+// when plans are compiled.
+func (b *binder) collect(format, key string) ([]string, string) {
+	if len(format) == 0 {
+		format = b.collection
+	}
+	if len(format) == 0 {
+		format = "multi"
+	}
+	sep := collectionFormats[format]
+	switch format {
+	case "brackets":
+		return b.listed[key], sep
+	case "indexed":
+	default:
+		return split(b.values[key], sep), sep
+	}
+
+	indexes := b.indexes(key)
+	if len(indexes) == 0 {
+		return nil, sep
+	}
+	vals := make([]string, indexes[len(indexes)-1]+1)
+	for i := range vals {
+		k := key + "." + strconv.Itoa(i)
+		if v := b.values[k]; len(v) > 0 {
+			vals[i] = v[0]
+		} else {
+			b.fail(k, "missing element", nil)
+		}
+	}
+	return vals, sep
+}
+
+// split splits every value of vals by sep if sep is not empty.
+func split(vals []string, sep string) []string {
+	if len(sep) == 0 {
+		return vals
+	}
+	var elems []string
+	for _, v := range vals {
+		if len(v) > 0 {
+			elems = append(elems, strings.Split(v, sep)...)
+		}
+	}
+	return elems
+}
+
+// hasPrefix reports whether there are values keyed by key or nested in
+// key.
+func (b *binder) hasPrefix(key string) bool {
+	if _, ok := b.values[key]; ok {
+		return true
+	}
+	prefix := key + "."
+	for k := range b.values {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexes returns the sorted indexes of the elements nested in key.
+// Keys with bad indexes are recorded as failed.
+func (b *binder) indexes(key string) []int {
+	var indexes []int
+	prefix := key + "."
+	for k := range b.values {
+		if !strings.HasPrefix(k, prefix) {
 			continue
 		}
+		s, _, _ := strings.Cut(k[len(prefix):], ".")
+		i, err := strconv.Atoi(s)
+		switch {
+		case err != nil || i < 0:
+			b.fail(prefix+s, "bad index", err)
+		case i >= maxBindIndex:
+			b.fail(prefix+s, "index out of range", nil)
+		default:
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
 
-		nvals := len(vals)
-		if kind == reflect.Slice && nvals > 0 {
-			sliceOf := fieldVal.Type().Elem().Kind()
-			slice := reflect.MakeSlice(fieldVal.Type(), nvals, nvals)
-			for j := 0; j < nvals; j++ {
-				if err := set(sliceOf, vals[j], slice.Index(j)); err != nil {
-					return err
-				}
+// isType reports whether t implements Type or encoding.TextUnmarshaler.
+func isType(t reflect.Type) bool {
+	return implements(t, typeType) || implements(t, textUnmarshalerType)
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// parse sets v from s if v implements Type or encoding.TextUnmarshaler,
+// and reports whether it does. Type takes precedence.
+func parse(v reflect.Value, s string) (bool, error) {
+	switch iface := v.Addr().Interface().(type) {
+	case Type:
+		return true, iface.Parse(s)
+	case encoding.TextUnmarshaler:
+		return true, iface.UnmarshalText([]byte(s))
+	}
+	return false, nil
+}
+
+// isLeaf reports whether values of type t are set from strings
+// directly, rather than from keys nested in the key of the field.
+func isLeaf(t reflect.Type) bool {
+	// we have to check if it is a self-defined type here.
+	// because types like net.IP (alias of []byte) is taken
+	// as of type slice by Go.
+	if isType(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return false
+	case reflect.Pointer:
+		return isLeaf(t.Elem())
+	case reflect.Slice:
+		e := t.Elem()
+		return isType(e) || e.Kind() != reflect.Slice && isLeaf(e)
+	}
+	return true
+}
+
+// modifiers are the modifiers that can be used in `mod` tags.
+var modifiers = map[string]func(string) string{
+	"trim":   strings.TrimSpace,
+	"ltrim":  func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) },
+	"rtrim":  func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) },
+	"lower":  strings.ToLower,
+	"upper":  strings.ToUpper,
+	"squash": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+}
+
+// compileModifiers returns the modifiers named by names, failing if any
+// of them is unknown.
+func compileModifiers(names []string) ([]func(string) string, error) {
+	mods := make([]func(string) string, len(names))
+	for i, name := range names {
+		mod, ok := modifiers[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown modifier %q", name)
+		}
+		mods[i] = mod
+	}
+	return mods, nil
+}
+
+// modify returns a copy of vals modified by mods in order.
+func modify(vals []string, mods []func(string) string) []string {
+	modified := make([]string, len(vals))
+	copy(modified, vals)
+	for _, mod := range mods {
+		for i, v := range modified {
+			modified[i] = mod(v)
+		}
+	}
+	return modified
+}
+
+// setValues sets the values to bind, whose bracketed keys are converted
+// to the dotted form. Values of keys ending with [] are kept in listed by
+// their converted keys as well, which are those of the brackets format.
+func (b *binder) setValues(values map[string][]string) {
+	b.values = normalizeKeys(values)
+	b.listed = nil
+	for k, vals := range values {
+		if strings.HasSuffix(k, "[]") {
+			if b.listed == nil {
+				b.listed = make(map[string][]string)
 			}
-			fieldVal.Set(slice)
-			continue
+			nk := normalizeKey(k)
+			b.listed[nk] = append(b.listed[nk], vals...)
 		}
-		if err := set(kind, vals[0], fieldVal); err != nil {
-			return err
+	}
+}
+
+// normalizeKeys converts bracketed keys in values to the dotted form.
+// values is returned as is if there is no such key.
+func normalizeKeys(values map[string][]string) map[string][]string {
+	found := false
+	for k := range values {
+		if strings.IndexByte(k, '[') >= 0 {
+			found = true
+			break
 		}
 	}
-	return nil
+	if !found {
+		return values
+	}
+
+	normalized := make(map[string][]string, len(values))
+	for k, vals := range values {
+		nk := normalizeKey(k)
+		normalized[nk] = append(normalized[nk], vals...)
+	}
+	return normalized
+}
+
+// normalizeKey converts a key like a[b][0].c or a[] to a.b.0.c or a.
+// Malformed keys are returned as is.
+func normalizeKey(k string) string {
+	i := strings.IndexByte(k, '[')
+	if i < 0 {
+		return k
+	}
+	var sb strings.Builder
+	sb.Grow(len(k))
+	sb.WriteString(k[:i])
+	for i < len(k) {
+		j := strings.IndexByte(k[i:], ']')
+		if j < 0 {
+			return k
+		}
+		if seg := k[i+1 : i+j]; len(seg) > 0 {
+			sb.WriteByte('.')
+			sb.WriteString(seg)
+		}
+		i += j + 1
+		// copy what follows up to the next bracket, like .c of [0].c
+		j = strings.IndexByte(k[i:], '[')
+		if j < 0 {
+			j = len(k) - i
+		}
+		sb.WriteString(k[i : i+j])
+		i += j
+	}
+	return sb.String()
 }
 
-func set(k reflect.Kind, s string, v reflect.Value) error {
+// setter sets v from s.
+type setter func(s string, v reflect.Value) error
+
+// newSetter returns the setter of values of type t, which is looked into
+// once rather than every time a value is set.
+func newSetter(t reflect.Type) setter {
+	k := t.Kind()
+	if k != reflect.Pointer && isType(t) {
+		// custom types of basic kinds, like an enum of int implementing
+		// encoding.TextUnmarshaler.
+		return setCustom
+	}
 	switch k {
 	case reflect.Pointer:
-		return setPtr(s, v)
-	case reflect.Int:
-		return setInt(s, 0, v)
-	case reflect.Int8:
-		return setInt(s, 8, v)
-	case reflect.Int16:
-		return setInt(s, 16, v)
-	case reflect.Int32:
-		return setInt(s, 32, v)
-	case reflect.Int64:
-		return setInt(s, 64, v)
-	case reflect.Uint:
-		return setUint(s, 0, v)
-	case reflect.Uint8:
-		return setUint(s, 8, v)
-	case reflect.Uint16:
-		return setUint(s, 16, v)
-	case reflect.Uint32:
-		return setUint(s, 32, v)
-	case reflect.Uint64:
-		return setUint(s, 64, v)
+		elem := newSetter(t.Elem())
+		return func(s string, v reflect.Value) error {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return elem(s, v.Elem())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := t.Bits()
+		return func(s string, v reflect.Value) error { return setInt(s, bits, v) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bits := t.Bits()
+		return func(s string, v reflect.Value) error { return setUint(s, bits, v) }
+	case reflect.Float32, reflect.Float64:
+		bits := t.Bits()
+		return func(s string, v reflect.Value) error { return setFloat(s, bits, v) }
 	case reflect.Bool:
-		return setBool(s, v)
-	case reflect.Float32:
-		return setFloat(s, 32, v)
-	case reflect.Float64:
-		return setFloat(s, 64, v)
+		return setBool
 	case reflect.String:
-		v.SetString(s)
-	default:
-		return setCustom(s, v)
+		return func(s string, v reflect.Value) error {
+			v.SetString(s)
+			return nil
+		}
 	}
-	return nil
+	return setCustom
 }
 
-func setInt(s string, bits int, v reflect.Value) error {
+// valuesSetter sets v from the values of a leaf field.
+type valuesSetter func(v reflect.Value, vals []string) error
+
+// newValuesSetter returns the valuesSetter of leaves of type t, which
+// sets slices from all of the values and the others from the first one.
+func newValuesSetter(t reflect.Type) valuesSetter {
+	pt := reflect.PointerTo(t)
+	if implements(pt, typeType) || implements(pt, textUnmarshalerType) {
+		return func(v reflect.Value, vals []string) error {
+			_, err := parse(v, vals[0])
+			return err
+		}
+	}
+	if t.Kind() == reflect.Slice {
+		elem := newSetter(t.Elem())
+		return func(v reflect.Value, vals []string) error {
+			slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+			for i, s := range vals {
+				if err := elem(s, slice.Index(i)); err != nil {
+					return err
+				}
+			}
+			v.Set(slice)
+			return nil
+		}
+	}
+	set := newSetter(t)
+	return func(v reflect.Value, vals []string) error {
+		return set(vals[0], v)
+	}
+}
+
+// parseInt, parseUint, parseFloat and parseBool parse s like strconv
+// does, but take an empty s as the zero value.
+func parseInt(s string, bits int) (int64, error) {
 	if len(s) == 0 {
 		s = "0"
 	}
-	i64, err := strconv.ParseInt(s, 10, bits)
+	return strconv.ParseInt(s, 10, bits)
+}
+
+func parseUint(s string, bits int) (uint64, error) {
+	if len(s) == 0 {
+		s = "0"
+	}
+	return strconv.ParseUint(s, 10, bits)
+}
+
+func parseFloat(s string, bits int) (float64, error) {
+	if len(s) == 0 {
+		s = "0.0"
+	}
+	return strconv.ParseFloat(s, bits)
+}
+
+func parseBool(s string) (bool, error) {
+	if len(s) == 0 {
+		s = "false"
+	}
+	return strconv.ParseBool(s)
+}
+
+func setInt(s string, bits int, v reflect.Value) error {
+	i64, err := parseInt(s, bits)
 	if err == nil {
 		v.SetInt(i64)
 	}
@@ -133,10 +917,7 @@ func setInt(s string, bits int, v reflect.Value) error {
 }
 
 func setUint(s string, bits int, v reflect.Value) error {
-	if len(s) == 0 {
-		s = "0"
-	}
-	u64, err := strconv.ParseUint(s, 10, bits)
+	u64, err := parseUint(s, bits)
 	if err == nil {
 		v.SetUint(u64)
 	}
@@ -144,10 +925,7 @@ func setUint(s string, bits int, v reflect.Value) error {
 }
 
 func setFloat(s string, bits int, v reflect.Value) error {
-	if len(s) == 0 {
-		s = "0.0"
-	}
-	f64, err := strconv.ParseFloat(s, bits)
+	f64, err := parseFloat(s, bits)
 	if err == nil {
 		v.SetFloat(f64)
 	}
@@ -155,27 +933,61 @@ func setFloat(s string, bits int, v reflect.Value) error {
 }
 
 func setBool(s string, v reflect.Value) error {
-	if len(s) == 0 {
-		s = "false"
-	}
-	b, err := strconv.ParseBool(s)
+	b, err := parseBool(s)
 	if err == nil {
 		v.SetBool(b)
 	}
 	return err
 }
 
-func setPtr(s string, v reflect.Value) error {
-	if v.IsNil() {
-		v.Set(reflect.New(v.Type().Elem()))
-	}
-	return set(v.Elem().Kind(), s, v.Elem())
-}
-
 func setCustom(s string, v reflect.Value) error {
-	iface, ok := v.Addr().Interface().(Type)
+	ok, err := parse(v, s)
 	if !ok {
 		return errors.New("bad type")
 	}
-	return iface.Parse(s)
+	return err
+}
+
+// layoutType reports whether t can be set in a layout by setTimes.
+func layoutType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice:
+		return layoutType(t.Elem())
+	}
+	return t.Kind() == reflect.Struct && t.ConvertibleTo(timeType)
+}
+
+func layoutError(t reflect.Type) error {
+	return fmt.Errorf("layout tag on field of type %s", t)
+}
+
+// setTimes sets v, which is a time.Time or a type defined by it like
+// Time, or a pointer to or a slice of such a type, from vals in layout.
+// It fails if v is of any other type, see layoutType.
+func setTimes(v reflect.Value, vals []string, layout string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setTimes(v.Elem(), vals, layout)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setTimes(slice.Index(i), []string{s}, layout); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Struct:
+		if v.Type().ConvertibleTo(timeType) {
+			t, err := time.Parse(layout, vals[0])
+			if err == nil {
+				v.Set(reflect.ValueOf(t).Convert(v.Type()))
+			}
+			return err
+		}
+	}
+	return layoutError(v.Type())
 }
@@ -1,7 +1,15 @@
 package hr
 
 import (
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type structAll struct {
@@ -63,3 +71,359 @@ func TestBindOptional(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type structAddress struct {
+	City   string `form:"city"`
+	Street string `form:"street?"`
+}
+
+type structItem struct {
+	SKU string `form:"sku"`
+	Qty int    `form:"qty?"`
+}
+
+type StructEmbedded struct {
+	Note string `form:"note"`
+}
+
+type structNested struct {
+	*StructEmbedded
+	Address  structAddress     `form:"address"`
+	Billing  *structAddress    `form:"billing?"`
+	Shipping *structAddress    `form:"shipping?"`
+	Items    []structItem      `form:"items"`
+	Tags     []string          `form:"tags"`
+	Meta     map[string]string `form:"meta?"`
+	Scores   map[string]int    `form:"scores?"`
+}
+
+func TestBindNested(t *testing.T) {
+	values := map[string][]string{
+		"note":            {"hello"},
+		"address.city":    {"Paris"},
+		"billing[city]":   {"Lyon"},
+		"billing[street]": {"Rue 1"},
+		"items[0][sku]":   {"A1"},
+		"items[0].qty":    {"2"},
+		"items[1].sku":    {"B2"},
+		"tags[]":          {"x", "y"},
+		"meta[k.1]":       {"v1"},
+		"meta[k2]":        {"v2"},
+		"scores[math]":    {"90"},
+	}
+	var a structNested
+	if err := bind(&a, values, "form"); err != nil {
+		t.Fatal(err)
+	}
+	want := structNested{
+		StructEmbedded: &StructEmbedded{Note: "hello"},
+		Address:        structAddress{City: "Paris"},
+		Billing:        &structAddress{City: "Lyon", Street: "Rue 1"},
+		Items:          []structItem{{"A1", 2}, {"B2", 0}},
+		Tags:           []string{"x", "y"},
+		Meta:           map[string]string{"k.1": "v1", "k2": "v2"},
+		Scores:         map[string]int{"math": 90},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Fatalf("want %+v\ngot  %+v", want, a)
+	}
+
+	cases := []struct {
+		values map[string][]string
+		err    string
+	}{
+		{map[string][]string{"note": {""}, "items[0].sku": {""}, "tags": {""}}, "missing field: address.city"},
+		{map[string][]string{"note": {""}}, "missing field: address.city; missing field: items; missing field: tags"},
+		{map[string][]string{"note": {""}, "address.city": {""}, "items[1].sku": {""}, "tags": {""}}, "missing field: items.0.sku"},
+		{map[string][]string{"note": {""}, "address.city": {""}, "items[x].sku": {""}, "tags": {""}}, "bad index: items.x"},
+		{map[string][]string{"note": {""}, "address.city": {""}, "items[5000].sku": {""}, "tags": {""}}, "index out of range: items.5000"},
+	}
+	for _, v := range cases {
+		var a structNested
+		err := bind(&a, v.values, "form")
+		if err == nil || err.Error() != v.err {
+			t.Fatalf("want error %q got %v", v.err, err)
+		}
+	}
+}
+
+type structTree struct {
+	Name     string        `form:"name"`
+	Parent   *structTree   `form:"parent?"`
+	Children []*structTree `form:"children?"`
+}
+
+func TestBindRecursive(t *testing.T) {
+	values := map[string][]string{
+		"name":              {"b"},
+		"parent.name":       {"a"},
+		"children[0][name]": {"c"},
+	}
+	var a structTree
+	if err := bind(&a, values, "form"); err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "b" || a.Parent == nil || a.Parent.Name != "a" || a.Parent.Parent != nil ||
+		len(a.Children) != 1 || a.Children[0].Name != "c" {
+		t.Fatalf("bad tree %+v", a)
+	}
+
+	delete(values, "parent.name")
+	values["parent.parent.name"] = []string{"z"}
+	if err := bind(&a, values, "form"); err == nil || err.Error() != "missing field: parent.name" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	cases := map[string]string{
+		"a":          "a",
+		"a.b":        "a.b",
+		"a[b]":       "a.b",
+		"a[b][c]":    "a.b.c",
+		"a[0].b":     "a.0.b",
+		"a[0][b][1]": "a.0.b.1",
+		"a[]":        "a",
+		"a[b":        "a[b",
+	}
+	for k, want := range cases {
+		if got := normalizeKey(k); got != want {
+			t.Fatalf("%s: want %s got %s", k, want, got)
+		}
+	}
+}
+
+func TestBindDefaultAndMod(t *testing.T) {
+	type search struct {
+		Query  string   `query:"q" mod:"squash,lower"`
+		Limit  int      `query:"limit" default:"20"`
+		Offset int      `query:"offset?"`
+		Sort   string   `query:"sort?" default:"name" mod:"trim"`
+		Tags   []string `query:"tags?" mod:"trim,upper"`
+	}
+
+	cases := []struct {
+		values map[string][]string
+		want   search
+	}{
+		{
+			map[string][]string{"q": {"  Hello   World "}},
+			search{Query: "hello world", Limit: 20, Sort: "name"},
+		},
+		{
+			map[string][]string{"q": {"go"}, "limit": {""}, "sort": {"  "}, "tags": {" a", "b "}},
+			search{Query: "go", Limit: 20, Sort: "name", Tags: []string{"A", "B"}},
+		},
+		{
+			map[string][]string{"q": {"go"}, "limit": {"5"}, "offset": {"10"}, "sort": {" date "}},
+			search{Query: "go", Limit: 5, Offset: 10, Sort: "date"},
+		},
+	}
+	for _, v := range cases {
+		var a search
+		if err := bind(&a, v.values, "query"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, v.want) {
+			t.Fatalf("want %+v got %+v", v.want, a)
+		}
+	}
+
+	var bad struct {
+		Nested struct {
+			Q string `query:"q" mod:"shout"`
+		} `query:"n"`
+	}
+	if err := bind(&bad, map[string][]string{"n.q": {"a"}}, "query"); err == nil || !strings.Contains(err.Error(), "unknown modifier") {
+		t.Fatalf("want unknown modifier error got %v", err)
+	}
+}
+
+type level int
+
+func (l *level) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("bad level")
+	}
+	return nil
+}
+
+func TestBindTextUnmarshaler(t *testing.T) {
+	type report struct {
+		At     time.Time   `query:"at"`
+		Day    time.Time   `query:"day" layout:"2006-01-02"`
+		Since  *Time       `query:"since?" layout:"02/01/2006 15:04"`
+		Days   []time.Time `query:"days?" layout:"20060102"`
+		Addr   netip.Addr  `query:"addr"`
+		Amount *big.Int    `query:"amount"`
+		Level  level       `query:"level"`
+		Levels []level     `query:"levels?"`
+	}
+
+	values := map[string][]string{
+		"at":     {"2023-06-05T21:33:45Z"},
+		"day":    {"2023-06-05"},
+		"since":  {"05/06/2023 21:33"},
+		"days":   {"20230605", "20230606"},
+		"addr":   {"192.168.0.1"},
+		"amount": {"123456789012345678901234567890"},
+		"level":  {"high"},
+		"levels": {"low", "high"},
+	}
+	var a report
+	if err := bind(&a, values, "query"); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2023, 6, 5, 0, 0, 0, 0, time.UTC)
+	amount, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if !a.At.Equal(day.Add(21*time.Hour+33*time.Minute+45*time.Second)) ||
+		!a.Day.Equal(day) ||
+		a.Since == nil || !time.Time(*a.Since).Equal(day.Add(21*time.Hour+33*time.Minute)) ||
+		len(a.Days) != 2 || !a.Days[1].Equal(day.AddDate(0, 0, 1)) ||
+		a.Addr != netip.MustParseAddr("192.168.0.1") ||
+		a.Amount.Cmp(amount) != 0 ||
+		a.Level != 2 || !reflect.DeepEqual(a.Levels, []level{1, 2}) {
+		t.Fatalf("bad report %+v", a)
+	}
+
+	values = map[string][]string{
+		"at":     {"yesterday"},
+		"day":    {"2023-06-05T21:33:45Z"},
+		"addr":   {"localhost"},
+		"amount": {"1.5"},
+		"level":  {"medium"},
+	}
+	err := bind(&a, values, "query")
+	want := "invalid value: at; invalid value: day; invalid value: addr; invalid value: amount; invalid value: level"
+	if err == nil || err.Error() != want {
+		t.Fatalf("want error %q got %v", want, err)
+	}
+
+	var bad struct {
+		N int `query:"n" layout:"2006"`
+	}
+	if err := bind(&bad, map[string][]string{"n": {"2023"}}, "query"); err == nil || !strings.Contains(err.Error(), "layout tag on field of type int") {
+		t.Fatalf("want layout error got %v", err)
+	}
+}
+
+func TestBindCollection(t *testing.T) {
+	type query struct {
+		Multi    []int    `query:"multi?"`
+		CSV      []int    `query:"csv?" collection:"csv"`
+		SSV      []string `query:"ssv?" collection:"ssv"`
+		Pipes    []string `query:"pipes?" collection:"pipes" mod:"trim"`
+		Brackets []int    `query:"brackets?" collection:"brackets"`
+		Indexed  []int    `query:"indexed?" collection:"indexed"`
+		Default  []int    `query:"default?" collection:"csv" default:"1,2"`
+	}
+
+	values := map[string][]string{
+		"multi":      {"1", "2"},
+		"csv":        {"1,2", "3"},
+		"ssv":        {"a b"},
+		"pipes":      {" a | b "},
+		"brackets[]": {"1", "2"},
+		"brackets":   {"3"},
+		"indexed[1]": {"2"},
+		"indexed[0]": {"1"},
+		"default":    {""},
+	}
+	want := query{
+		Multi:    []int{1, 2},
+		CSV:      []int{1, 2, 3},
+		SSV:      []string{"a", "b"},
+		Pipes:    []string{"a", "b"},
+		Brackets: []int{1, 2},
+		Indexed:  []int{1, 2},
+		Default:  []int{1, 2},
+	}
+	var a query
+	if err := bind(&a, values, "query"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Fatalf("want %+v got %+v", want, a)
+	}
+
+	// elements missing from indexes are failed rather than zero.
+	err := bind(&a, map[string][]string{"indexed[0]": {"1"}, "indexed[2]": {"3"}}, "query")
+	if berr, ok := err.(*BindError); !ok || len(berr.Fields) != 1 || berr.Fields[0].Key != "indexed.1" {
+		t.Fatalf("want missing element indexed.1 got %v", err)
+	}
+
+	var bad struct {
+		IDs []int `query:"ids" collection:"tsv"`
+	}
+	if err := bind(&bad, map[string][]string{"ids": {"1"}}, "query"); err == nil || !strings.Contains(err.Error(), "unknown collection format") {
+		t.Fatalf("want unknown collection format got %v", err)
+	}
+
+	// the default format of the router.
+	r := Default()
+	r.CollectionFormat("csv")
+	var got struct {
+		IDs  []int `query:"ids"`
+		Tags []int `header:"X-Tags" collection:"multi"`
+	}
+	r.GET("/", func(c *Ctx) error {
+		if err := c.Bind(&got); err != nil {
+			return err
+		}
+		return c.BindHeader(&got)
+	})
+	req, _ := http.NewRequest("GET", "/?ids=1,2&ids=3", nil)
+	req.Header.Add("X-Tags", "4")
+	req.Header.Add("X-Tags", "5")
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK || !reflect.DeepEqual(got.IDs, []int{1, 2, 3}) || !reflect.DeepEqual(got.Tags, []int{4, 5}) {
+		t.Fatalf("bad response %d %s %+v", rw.Code, rw.Body, got)
+	}
+}
+
+func BenchmarkBind(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var a structAll
+		if err := bind(&a, valuesAll, "query"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBindNested(b *testing.B) {
+	values := map[string][]string{
+		"note":          {"hello"},
+		"address.city":  {"Paris"},
+		"billing.city":  {"Lyon"},
+		"items.0.sku":   {"A1"},
+		"items.0.qty":   {"2"},
+		"items.1.sku":   {"B2"},
+		"tags":          {"x", "y"},
+		"meta.k1":       {"v1"},
+		"scores.math":   {"90"},
+		"scores.poetry": {"80"},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var a structNested
+		if err := bind(&a, values, "form"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCompilePlan measures what every bind would cost more if plans
+// were not cached.
+func BenchmarkCompilePlan(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		compilePlan(reflect.TypeOf(structNested{}), "form", make(map[reflect.Type]*plan))
+	}
+}
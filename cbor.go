@@ -0,0 +1,843 @@
+package hr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cborCodec is a codec of CBOR (RFC 8949), the Concise Binary Object
+// Representation. Structs are encoded as maps keyed by field names,
+// which can be renamed or omitted by `cbor` tags, falling back to `json`
+// tags, in the same way as encoding/json does. time.Time is encoded as
+// an RFC 3339 string with tag 0, and types implementing
+// encoding.TextMarshaler are encoded as text strings.
+type cborCodec struct{}
+
+func (cborCodec) MediaType() string { return "application/cbor" }
+
+func (cborCodec) Encode(w io.Writer, v interface{}) error {
+	var e cborEncoder
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return err
+	}
+	_, err := w.Write(e.buf)
+	return err
+}
+
+func (c cborCodec) Decode(r io.Reader, v interface{}) error {
+	return c.decode(r, v, &DecodeOptions{})
+}
+
+func (cborCodec) decode(r io.Reader, v interface{}, opts *DecodeOptions) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("cbor: decoding into a non-pointer")
+	}
+	br, ok := r.(cborReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	d := cborDecoder{r: br, maxDepth: cborMaxDepth}
+	if opts.MaxDepth > 0 && opts.MaxDepth < cborMaxDepth {
+		d.maxDepth = opts.MaxDepth
+	}
+	x, err := d.item(0)
+	if err == errCBORBreak {
+		return errors.New("cbor: unexpected break")
+	}
+	if err != nil {
+		return err
+	}
+	if opts.DisallowTrailingData {
+		if _, err := br.ReadByte(); err != io.EOF {
+			return fmt.Errorf("cbor: %w", errTrailingData)
+		}
+	}
+	return cborAssign(rv.Elem(), x)
+}
+
+// major types of CBOR data items.
+const (
+	cborUint byte = iota
+	cborNint
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// cborMaxDepth limits the nesting of data items to be decoded.
+const cborMaxDepth = 512
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type cborField struct {
+	name      string
+	index     []int
+	omitempty bool
+}
+
+var cborFieldCache sync.Map // map[reflect.Type][]cborField
+
+// cborFields returns the encodable fields of the struct type t.
+func cborFields(t reflect.Type) []cborField {
+	if fs, ok := cborFieldCache.Load(t); ok {
+		return fs.([]cborField)
+	}
+	fs := appendCBORFields(nil, t, nil)
+	cborFieldCache.Store(t, fs)
+	return fs
+}
+
+func appendCBORFields(fs []cborField, t reflect.Type, index []int) []cborField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("cbor")
+		if !ok {
+			tag = f.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(append([]int{}, index...), i)
+		// fields of embedded structs are promoted like encoding/json does.
+		if f.Anonymous && len(name) == 0 && f.Type.Kind() == reflect.Struct {
+			fs = appendCBORFields(fs, f.Type, idx)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		fs = append(fs, cborField{
+			name:      name,
+			index:     idx,
+			omitempty: strings.Contains(opts, "omitempty"),
+		})
+	}
+	return fs
+}
+
+type cborEncoder struct {
+	buf []byte
+}
+
+// head appends the initial bytes of a data item of the major type with
+// the argument n.
+func (e *cborEncoder) head(major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, m|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, m|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, m|26), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, m|27), n)
+	}
+}
+
+func (e *cborEncoder) text(s string) {
+	e.head(cborText, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *cborEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, 0xf6) // null
+		return nil
+	}
+	t := v.Type()
+	if t == timeType {
+		e.head(cborTag, 0)
+		e.text(v.Interface().(time.Time).Format(time.RFC3339Nano))
+		return nil
+	}
+	if t.Implements(textMarshalerType) && !(t.Kind() == reflect.Pointer && v.IsNil()) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.text(string(b))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xf5)
+		} else {
+			e.buf = append(e.buf, 0xf4)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := v.Int(); i >= 0 {
+			e.head(cborUint, uint64(i))
+		} else {
+			e.head(cborNint, uint64(-1-i))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.head(cborUint, v.Uint())
+	case reflect.Float32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xfa), math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xfb), math.Float64bits(v.Float()))
+	case reflect.String:
+		e.text(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xf6)
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.head(cborBytes, uint64(v.Len()))
+			e.buf = append(e.buf, v.Bytes()...)
+			return nil
+		}
+		return e.array(v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.head(cborBytes, uint64(v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				e.buf = append(e.buf, byte(v.Index(i).Uint()))
+			}
+			return nil
+		}
+		return e.array(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xf6)
+			return nil
+		}
+		return e.dict(v)
+	case reflect.Struct:
+		return e.record(v)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xf6)
+			return nil
+		}
+		return e.encode(v.Elem())
+	default:
+		return fmt.Errorf("cbor: unsupported type %s", t)
+	}
+	return nil
+}
+
+func (e *cborEncoder) array(v reflect.Value) error {
+	e.head(cborArray, uint64(v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dict encodes a map with its keys sorted in the bytewise order of their
+// encodings, which makes the encoding deterministic.
+func (e *cborEncoder) dict(v reflect.Value) error {
+	type pair struct{ k, v []byte }
+	pairs := make([]pair, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		var k, x cborEncoder
+		if err := k.encode(iter.Key()); err != nil {
+			return err
+		}
+		if err := x.encode(iter.Value()); err != nil {
+			return err
+		}
+		pairs = append(pairs, pair{k.buf, x.buf})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return bytes.Compare(pairs[i].k, pairs[j].k) < 0
+	})
+	e.head(cborMap, uint64(len(pairs)))
+	for _, p := range pairs {
+		e.buf = append(append(e.buf, p.k...), p.v...)
+	}
+	return nil
+}
+
+func (e *cborEncoder) record(v reflect.Value) error {
+	fields := cborFields(v.Type())
+	n := 0
+	for _, f := range fields {
+		if !f.omitempty || !isEmptyValue(v.FieldByIndex(f.index)) {
+			n++
+		}
+	}
+	e.head(cborMap, uint64(n))
+	for _, f := range fields {
+		fv := v.FieldByIndex(f.index)
+		if f.omitempty && isEmptyValue(fv) {
+			continue
+		}
+		e.text(f.name)
+		if err := e.encode(fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmptyValue reports whether v is empty in the sense of the omitempty
+// option of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return v.IsZero()
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+type cborReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// errCBORBreak is returned by cborDecoder.item when it meets the break
+// stop code ending an indefinite-length data item.
+var errCBORBreak = errors.New("cbor: break")
+
+// cborPair is a key-value pair of a decoded map.
+type cborPair struct {
+	k, v interface{}
+}
+
+// cborTagged is a decoded data item with a tag.
+type cborTagged struct {
+	tag     uint64
+	content interface{}
+}
+
+// cborDecoder decodes data items into an intermediate form: uint64 for
+// unsigned integers, int64 for negative integers, float64, bool, nil,
+// []byte, string, []interface{} for arrays, []cborPair for maps and
+// cborTagged for tagged data items.
+type cborDecoder struct {
+	r        cborReader
+	maxDepth int
+}
+
+func (d *cborDecoder) head() (major, info byte, n uint64, err error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b>>5, b&0x1f
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		var buf [8]byte
+		size := 1 << (info - 24)
+		if _, err = io.ReadFull(d.r, buf[8-size:]); err != nil {
+			return 0, 0, 0, unexpectedEOF(err)
+		}
+		n = binary.BigEndian.Uint64(buf[:])
+	case info == 31:
+		if major == cborUint || major == cborNint || major == cborTag {
+			return 0, 0, 0, errors.New("cbor: malformed data item")
+		}
+	default:
+		return 0, 0, 0, errors.New("cbor: malformed data item")
+	}
+	return major, info, n, nil
+}
+
+func (d *cborDecoder) item(depth int) (interface{}, error) {
+	if depth > d.maxDepth {
+		return nil, fmt.Errorf("cbor: %w of %d", errTooDeep, d.maxDepth)
+	}
+	major, info, n, err := d.head()
+	if err != nil {
+		if depth > 0 {
+			err = unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		return n, nil
+	case cborNint:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflows int64")
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		b, err := d.str(major, info, n)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(b), nil
+		}
+		return b, nil
+	case cborArray:
+		var a []interface{}
+		for i := uint64(0); info == 31 || i < n; i++ {
+			x, err := d.item(depth + 1)
+			if err == errCBORBreak && info == 31 {
+				break
+			}
+			if err != nil {
+				return nil, cborMalformed(err)
+			}
+			a = append(a, x)
+		}
+		if a == nil {
+			a = []interface{}{}
+		}
+		return a, nil
+	case cborMap:
+		var m []cborPair
+		for i := uint64(0); info == 31 || i < n; i++ {
+			k, err := d.item(depth + 1)
+			if err == errCBORBreak && info == 31 {
+				break
+			}
+			if err != nil {
+				return nil, cborMalformed(err)
+			}
+			v, err := d.item(depth + 1)
+			if err != nil {
+				return nil, cborMalformed(err)
+			}
+			m = append(m, cborPair{k, v})
+		}
+		if m == nil {
+			m = []cborPair{}
+		}
+		return m, nil
+	case cborTag:
+		x, err := d.item(depth + 1)
+		if err != nil {
+			return nil, cborMalformed(err)
+		}
+		return cborTagged{n, x}, nil
+	}
+
+	// major type 7: simple values and floats.
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null and undefined
+		return nil, nil
+	case 25:
+		return halfToFloat(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	case 31:
+		return nil, errCBORBreak
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+// str reads the content of a byte or text string, which is the
+// concatenation of its chunks if it is of indefinite length.
+func (d *cborDecoder) str(major, info byte, n uint64) ([]byte, error) {
+	if info != 31 {
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: string too long")
+		}
+		// copy rather than allocating n bytes at once to not trust
+		// the length given by the data.
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return buf.Bytes(), nil
+	}
+	var b []byte
+	for {
+		m, info, n, err := d.head()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if m == cborSimple && info == 31 {
+			return b, nil
+		}
+		if m != major || info == 31 {
+			return nil, errors.New("cbor: malformed indefinite-length string")
+		}
+		chunk, err := d.str(major, info, n)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+// cborMalformed turns a break stop code met where a data item is
+// expected into an error, so that it does not end any enclosing
+// indefinite-length item by mistake.
+func cborMalformed(err error) error {
+	if err == errCBORBreak {
+		return errors.New("cbor: malformed data item")
+	}
+	return err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// halfToFloat converts an IEEE 754 half-precision float to a float64.
+func halfToFloat(h uint16) float64 {
+	exp, frac := (h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(frac, -24)
+	case 31:
+		if frac == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(frac+1024, int(exp)-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// cborGeneric converts a decoded data item into a value that fits in an
+// empty interface: maps become map[string]interface{} if all their keys
+// are strings or map[interface{}]interface{} otherwise, and tagged date
+// times become time.Time.
+func cborGeneric(x interface{}) (interface{}, error) {
+	switch x := x.(type) {
+	case []interface{}:
+		a := make([]interface{}, len(x))
+		for i, v := range x {
+			var err error
+			if a[i], err = cborGeneric(v); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case []cborPair:
+		strs := true
+		for _, p := range x {
+			if _, ok := p.k.(string); !ok {
+				strs = false
+				break
+			}
+		}
+		if strs {
+			m := make(map[string]interface{}, len(x))
+			for _, p := range x {
+				v, err := cborGeneric(p.v)
+				if err != nil {
+					return nil, err
+				}
+				m[p.k.(string)] = v
+			}
+			return m, nil
+		}
+		m := make(map[interface{}]interface{}, len(x))
+		for _, p := range x {
+			k, err := cborGeneric(p.k)
+			if err != nil {
+				return nil, err
+			}
+			if k != nil && !reflect.TypeOf(k).Comparable() {
+				return nil, errors.New("cbor: unhashable map key")
+			}
+			v, err := cborGeneric(p.v)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case cborTagged:
+		if x.tag == 0 || x.tag == 1 {
+			return cborTime(x.content)
+		}
+		return cborGeneric(x.content)
+	}
+	return x, nil
+}
+
+// cborTime converts the content of a date time tag, which is either an
+// RFC 3339 string or the seconds since the epoch.
+func cborTime(x interface{}) (time.Time, error) {
+	switch x := x.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, x)
+	case uint64:
+		return time.Unix(int64(x), 0), nil
+	case int64:
+		return time.Unix(x, 0), nil
+	case float64:
+		sec, frac := math.Modf(x)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("cbor: cannot decode %s into time.Time", cborDesc(x))
+}
+
+func cborDesc(x interface{}) string {
+	switch x.(type) {
+	case uint64, int64:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	case []byte:
+		return "byte string"
+	case string:
+		return "text string"
+	case []interface{}:
+		return "array"
+	case []cborPair:
+		return "map"
+	}
+	return "tagged item"
+}
+
+// cborAssign stores the decoded data item x in v.
+func cborAssign(v reflect.Value, x interface{}) error {
+	t := v.Type()
+	if tagged, ok := x.(cborTagged); ok {
+		if t == timeType && (tagged.tag == 0 || tagged.tag == 1) {
+			tm, err := cborTime(tagged.content)
+			if err == nil {
+				v.Set(reflect.ValueOf(tm))
+			}
+			return err
+		}
+		if t.Kind() != reflect.Interface {
+			x = tagged.content
+		}
+	}
+	if x == nil {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cbor: cannot decode %s into %s", cborDesc(x), t)
+	}
+
+	if t == timeType {
+		if s, ok := x.(string); ok {
+			tm, err := time.Parse(time.RFC3339Nano, s)
+			if err == nil {
+				v.Set(reflect.ValueOf(tm))
+			}
+			return err
+		}
+		return mismatch()
+	}
+	if t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		if s, ok := x.(string); ok {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return cborAssign(v.Elem(), x)
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return mismatch()
+		}
+		g, err := cborGeneric(x)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(g))
+	case reflect.Bool:
+		b, ok := x.(bool)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch x := x.(type) {
+		case uint64:
+			if x > math.MaxInt64 {
+				return fmt.Errorf("cbor: %d overflows %s", x, t)
+			}
+			i = int64(x)
+		case int64:
+			i = x
+		default:
+			return mismatch()
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("cbor: %d overflows %s", i, t)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, ok := x.(uint64)
+		if !ok {
+			return mismatch()
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("cbor: %d overflows %s", u, t)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch x := x.(type) {
+		case float64:
+			v.SetFloat(x)
+		case uint64:
+			v.SetFloat(float64(x))
+		case int64:
+			v.SetFloat(float64(x))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		s, ok := x.(string)
+		if !ok {
+			return mismatch()
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if b, ok := x.([]byte); ok && t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append(make([]byte, 0, len(b)), b...))
+			return nil
+		}
+		a, ok := x.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		s := reflect.MakeSlice(t, len(a), len(a))
+		for i, e := range a {
+			if err := cborAssign(s.Index(i), e); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		if b, ok := x.([]byte); ok && t.Elem().Kind() == reflect.Uint8 {
+			if len(b) > v.Len() {
+				return fmt.Errorf("cbor: %d bytes overflow %s", len(b), t)
+			}
+			v.Set(reflect.Zero(t))
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+		a, ok := x.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if len(a) > v.Len() {
+			return fmt.Errorf("cbor: %d items overflow %s", len(a), t)
+		}
+		v.Set(reflect.Zero(t))
+		for i, e := range a {
+			if err := cborAssign(v.Index(i), e); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		pairs, ok := x.([]cborPair)
+		if !ok {
+			return mismatch()
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(pairs)))
+		}
+		for _, p := range pairs {
+			k := reflect.New(t.Key()).Elem()
+			if err := cborAssign(k, p.k); err != nil {
+				return err
+			}
+			if !k.Comparable() {
+				return errors.New("cbor: unhashable map key")
+			}
+			e := reflect.New(t.Elem()).Elem()
+			if err := cborAssign(e, p.v); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, e)
+		}
+	case reflect.Struct:
+		pairs, ok := x.([]cborPair)
+		if !ok {
+			return mismatch()
+		}
+		fields := cborFields(t)
+		for _, p := range pairs {
+			name, ok := p.k.(string)
+			if !ok {
+				continue
+			}
+			if f := lookupCBORField(fields, name); f != nil {
+				if err := cborAssign(v.FieldByIndex(f.index), p.v); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// lookupCBORField returns the field named name, preferring an exact
+// match over a case-insensitive one.
+func lookupCBORField(fields []cborField, name string) *cborField {
+	var fold *cborField
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, name) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
@@ -0,0 +1,149 @@
+package hr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCBOREncode(t *testing.T) {
+	// test vectors from RFC 8949 appendix A.
+	cases := []struct {
+		v    interface{}
+		want string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{1000000, "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{float32(100000), "fa47c35000"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]interface{}{1, []int{2, 3}, []int{4, 5}}, "8301820203820405"},
+		{map[string]interface{}{"a": 1, "b": []int{2, 3}}, "a26161016162820203"},
+		{map[int]int{3: 4, 1: 2}, "a201020304"},
+		{time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), "c074323031332d30332d32315432303a30343a30305a"},
+	}
+	for _, v := range cases {
+		var buf bytes.Buffer
+		if err := (cborCodec{}).Encode(&buf, v.v); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(buf.Bytes()); got != v.want {
+			t.Fatalf("%v: want %s got %s", v.v, v.want, got)
+		}
+	}
+}
+
+func TestCBORDecode(t *testing.T) {
+	cases := []struct {
+		data string
+		want interface{}
+	}{
+		{"1a000f4240", uint64(1000000)},
+		{"3903e7", int64(-1000)},
+		{"f93c00", 1.0},
+		{"f9c400", -4.0},
+		{"f97c00", math.Inf(1)},
+		{"fa47c35000", 100000.0},
+		{"f6", nil},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}}},
+		{"bf61610161629f0203ffff", map[string]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}}},
+		{"a201020304", map[interface{}]interface{}{uint64(1): uint64(2), uint64(3): uint64(4)}},
+		{"c11a514b67b0", time.Unix(1363896240, 0)},
+	}
+	for _, v := range cases {
+		b, _ := hex.DecodeString(v.data)
+		var got interface{}
+		if err := (cborCodec{}).Decode(bytes.NewReader(b), &got); err != nil {
+			t.Fatalf("%s: %v", v.data, err)
+		}
+		if !reflect.DeepEqual(got, v.want) {
+			t.Fatalf("%s: want %#v got %#v", v.data, v.want, got)
+		}
+	}
+
+	malformed := []string{"", "18", "9f01", "81ff", "bf6161ff", "1c", "62c3"}
+	for _, v := range malformed {
+		b, _ := hex.DecodeString(v)
+		var got interface{}
+		if err := (cborCodec{}).Decode(bytes.NewReader(b), &got); err == nil {
+			t.Fatalf("%s: want error got %#v", v, got)
+		}
+	}
+
+	// {"m": {[1]: 2}} has a key unhashable in Go.
+	b, _ := hex.DecodeString("a1616da1810102")
+	var m struct {
+		M map[interface{}]int `json:"m"`
+	}
+	if err := (cborCodec{}).Decode(bytes.NewReader(b), &m); err == nil || !strings.Contains(err.Error(), "unhashable") {
+		t.Fatalf("want unhashable key error got %v", err)
+	}
+}
+
+type cborInner struct {
+	Tags []string
+}
+
+type cborStruct struct {
+	cborInner
+	Name    string            `json:"name"`
+	Age     uint8             `cbor:"age"`
+	Score   float64           `json:"score,omitempty"`
+	Skipped string            `json:"-"`
+	Ptr     *int              `json:"ptr"`
+	Meta    map[string]string `json:"meta"`
+	Time    time.Time         `json:"time"`
+	IP      IP                `json:"ip"`
+	private int
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	n := 42
+	a := cborStruct{
+		cborInner: cborInner{Tags: []string{"a", "b"}},
+		Name:      "Bob",
+		Age:       42,
+		Skipped:   "skipped",
+		Ptr:       &n,
+		Meta:      map[string]string{"k": "v"},
+		Time:      time.Date(2023, 6, 5, 21, 33, 45, 0, time.UTC),
+		IP:        IP{127, 0, 0, 1},
+	}
+	var buf bytes.Buffer
+	if err := (cborCodec{}).Encode(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	var b cborStruct
+	if err := (cborCodec{}).Decode(&buf, &b); err != nil {
+		t.Fatal(err)
+	}
+	a.Skipped = ""
+	a.IP = nil // IP is not a encoding.TextUnmarshaler.
+	b.IP = nil
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("want %#v\ngot  %#v", a, b)
+	}
+
+	var overflow struct{ Age int8 }
+	buf.Reset()
+	(cborCodec{}).Encode(&buf, map[string]int{"Age": 1000})
+	if err := (cborCodec{}).Decode(&buf, &overflow); err == nil {
+		t.Fatal("want overflow error")
+	}
+}
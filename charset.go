@@ -0,0 +1,144 @@
+package hr
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CharsetDecoder returns a reader that decodes text in a charset read
+// from r into UTF-8.
+type CharsetDecoder func(r io.Reader) io.Reader
+
+var charsets = struct {
+	sync.RWMutex
+	m map[string]CharsetDecoder
+}{m: map[string]CharsetDecoder{
+	"iso-8859-1":   latin1Decoder,
+	"iso_8859-1":   latin1Decoder,
+	"latin1":       latin1Decoder,
+	"l1":           latin1Decoder,
+	"windows-1252": windows1252Decoder,
+	"cp1252":       windows1252Decoder,
+}}
+
+// RegisterCharset registers the decoder of a charset so that request
+// bodies in the charset can be bound. Charset names are case-insensitive.
+// UTF-8, US-ASCII, ISO-8859-1 and Windows-1252 are supported out of the
+// box, and decoders of golang.org/x/text can be registered for others,
+// for example:
+//
+//	hr.RegisterCharset("shift_jis", japanese.ShiftJIS.NewDecoder().Reader)
+func RegisterCharset(name string, dec CharsetDecoder) {
+	charsets.Lock()
+	charsets.m[strings.ToLower(name)] = dec
+	charsets.Unlock()
+}
+
+// charsetDecoder returns the decoder of the charset named name, or nil
+// if text in the charset is already valid UTF-8.
+func charsetDecoder(name string) (CharsetDecoder, error) {
+	name = strings.ToLower(name)
+	switch name {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return nil, nil
+	}
+	charsets.RLock()
+	dec, ok := charsets.m[name]
+	charsets.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset %q", name)
+	}
+	return dec, nil
+}
+
+// charsetReader is used as the CharsetReader of XML decoders to decode
+// documents declaring a non-UTF-8 encoding.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	dec, err := charsetDecoder(label)
+	if err != nil || dec == nil {
+		return input, err
+	}
+	return dec(input), nil
+}
+
+// utf8Reader marks a request body that has been decoded into UTF-8 by
+// the charset given in Content-Type, which takes precedence over what
+// the body itself declares.
+type utf8Reader struct {
+	io.Reader
+}
+
+// transcodeForm decodes the keys and values of form from a charset into
+// UTF-8.
+func transcodeForm(form url.Values, dec CharsetDecoder) (url.Values, error) {
+	transcode := func(s string) (string, error) {
+		b, err := io.ReadAll(dec(strings.NewReader(s)))
+		return string(b), err
+	}
+	vals := make(url.Values, len(form))
+	for k, vs := range form {
+		key, err := transcode(k)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vs {
+			val, err := transcode(v)
+			if err != nil {
+				return nil, err
+			}
+			vals[key] = append(vals[key], val)
+		}
+	}
+	return vals, nil
+}
+
+// singleByteReader decodes text in a single-byte charset into UTF-8.
+type singleByteReader struct {
+	r       io.Reader
+	table   *[256]rune
+	in      [512]byte
+	pending []byte
+	err     error
+}
+
+func (d *singleByteReader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 && d.err == nil {
+		n, err := d.r.Read(d.in[:])
+		for _, b := range d.in[:n] {
+			d.pending = utf8.AppendRune(d.pending, d.table[b])
+		}
+		d.err = err
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	if len(d.pending) == 0 && d.err != nil {
+		return n, d.err
+	}
+	return n, nil
+}
+
+var latin1Table, windows1252Table [256]rune
+
+func init() {
+	for i := range latin1Table {
+		latin1Table[i] = rune(i)
+	}
+	windows1252Table = latin1Table
+	// Windows-1252 differs from ISO-8859-1 in the range 0x80-0x9f only.
+	copy(windows1252Table[0x80:], []rune{
+		'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+		0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+	})
+}
+
+func latin1Decoder(r io.Reader) io.Reader {
+	return &singleByteReader{r: r, table: &latin1Table}
+}
+
+func windows1252Decoder(r io.Reader) io.Reader {
+	return &singleByteReader{r: r, table: &windows1252Table}
+}
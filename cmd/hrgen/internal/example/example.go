@@ -0,0 +1,81 @@
+// Package example has structs given BindHR methods by hrgen, which are
+// tested against binding by reflection.
+package example
+
+import (
+	"mime/multipart"
+	"strings"
+	"time"
+
+	"github.com/tekqer/hr"
+)
+
+//go:generate go run github.com/tekqer/hr/cmd/hrgen
+
+// Level is bound by encoding.TextUnmarshaler.
+type Level int
+
+func (l *Level) UnmarshalText(b []byte) error {
+	switch strings.ToLower(string(b)) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return hr.BadRequest("bad level %q", b)
+	}
+	return nil
+}
+
+// Count is bound as an int.
+type Count int
+
+// IDs is bound as a slice of ints.
+type IDs []int
+
+// Paging is embedded in structs.
+type Paging struct {
+	Page int `query:"page" default:"1"`
+	Size int `query:"size?" default:"20"`
+}
+
+//hr:bind
+type ListOrders struct {
+	Paging
+	Org     int          `path:"org"`
+	Status  string       `query:"status?" mod:"trim,lower"`
+	IDs     IDs          `query:"ids?" collection:"csv"`
+	Tags    []string     `query:"tags?"`
+	Since   *hr.Date     `query:"since?"`
+	Until   time.Time    `query:"until?" layout:"2006-01-02"`
+	Level   Level        `query:"level?"`
+	Levels  []Level      `query:"levels?" collection:"pipes"`
+	Limit   *uint16      `query:"limit?"`
+	Count   Count        `query:"count?"`
+	Ratio   float64      `query:"ratio?"`
+	Exact   bool         `query:"exact?"`
+	Color   hr.Enum[rgb] `query:"color?"`
+	Tenant  string       `header:"X-Tenant"`
+	Session string       `cookie:"session?"`
+	private string       `query:"private"`
+}
+
+type rgb struct{}
+
+func (rgb) Values() []string { return []string{"red", "green", "blue"} }
+
+// CreateOrder is bound from forms, where query strings give what forms
+// do not.
+//
+//hr:bind
+type CreateOrder struct {
+	Org   int       `path:"org"`
+	SKU   string    `form:"sku" query:"sku?" validate:"required"`
+	Qty   int8      `form:"qty" default:"1" validate:"min=1"`
+	Notes []string  `form:"notes?"`
+	ID    hr.UUID   `form:"id?"`
+	When  *hr.Time  `form:"when?"`
+	Dates []hr.Date `form:"dates?" collection:"indexed"`
+
+	Receipt *multipart.FileHeader `file:"receipt?"`
+}
@@ -0,0 +1,55 @@
+// Code generated by hrgen. DO NOT EDIT.
+
+package example
+
+import (
+	"github.com/tekqer/hr"
+)
+
+// BindHR binds the request to v like hr.Ctx.Bind does, without reflection.
+func (v *ListOrders) BindHR(c *hr.Ctx) error {
+	return c.BindFunc(v, func(s *hr.Source) {
+		switch s.Tag() {
+		case "path":
+			hr.BindValue(s, &v.Org, "Org", "org", hr.Tags{})
+		case "query":
+			hr.BindValue(s, &v.Paging.Page, "Paging.Page", "page", hr.Tags{Default: "1", HasDefault: true})
+			hr.BindValue(s, &v.Paging.Size, "Paging.Size", "size", hr.Tags{Optional: true, Default: "20", HasDefault: true})
+			hr.BindValue(s, &v.Status, "Status", "status", hr.Tags{Optional: true, Mod: "trim,lower"})
+			hr.BindSlice(s, (*[]int)(&v.IDs), "IDs", "ids", hr.Tags{Optional: true, Collection: "csv"})
+			hr.BindSlice(s, &v.Tags, "Tags", "tags", hr.Tags{Optional: true})
+			hr.BindPointer(s, &v.Since, "Since", "since", hr.Tags{Optional: true})
+			hr.BindValue(s, &v.Until, "Until", "until", hr.Tags{Optional: true, Layout: "2006-01-02"})
+			hr.BindValue(s, &v.Level, "Level", "level", hr.Tags{Optional: true})
+			hr.BindSlice(s, &v.Levels, "Levels", "levels", hr.Tags{Optional: true, Collection: "pipes"})
+			hr.BindPointer(s, &v.Limit, "Limit", "limit", hr.Tags{Optional: true})
+			hr.BindValue(s, (*int)(&v.Count), "Count", "count", hr.Tags{Optional: true})
+			hr.BindValue(s, &v.Ratio, "Ratio", "ratio", hr.Tags{Optional: true})
+			hr.BindValue(s, &v.Exact, "Exact", "exact", hr.Tags{Optional: true})
+			hr.BindValue(s, &v.Color, "Color", "color", hr.Tags{Optional: true})
+		case "header":
+			hr.BindValue(s, &v.Tenant, "Tenant", "X-Tenant", hr.Tags{})
+		case "cookie":
+			hr.BindValue(s, &v.Session, "Session", "session", hr.Tags{Optional: true})
+		}
+	})
+}
+
+// BindHR binds the request to v like hr.Ctx.Bind does, without reflection.
+func (v *CreateOrder) BindHR(c *hr.Ctx) error {
+	return c.BindFunc(v, func(s *hr.Source) {
+		switch s.Tag() {
+		case "path":
+			hr.BindValue(s, &v.Org, "Org", "org", hr.Tags{})
+		case "query":
+			hr.BindValue(s, &v.SKU, "SKU", "sku", hr.Tags{Optional: true})
+		case "form":
+			hr.BindValue(s, &v.SKU, "SKU", "sku", hr.Tags{})
+			hr.BindValue(s, &v.Qty, "Qty", "qty", hr.Tags{Default: "1", HasDefault: true})
+			hr.BindSlice(s, &v.Notes, "Notes", "notes", hr.Tags{Optional: true})
+			hr.BindValue(s, &v.ID, "ID", "id", hr.Tags{Optional: true})
+			hr.BindPointer(s, &v.When, "When", "when", hr.Tags{Optional: true})
+			hr.BindSlice(s, &v.Dates, "Dates", "dates", hr.Tags{Optional: true, Collection: "indexed"})
+		}
+	})
+}
@@ -0,0 +1,355 @@
+// Command hrgen generates BindHR methods which bind requests to structs
+// like hr.Ctx.Bind does, but without reflection. Structs to generate for
+// are annotated with a //hr:bind line in their doc comments:
+//
+//	//go:generate go run github.com/tekqer/hr/cmd/hrgen
+//
+//	//hr:bind
+//	type ListUsers struct {
+//	    Org  int      `path:"org"`
+//	    Page int      `query:"page" default:"1"`
+//	    Tags []string `query:"tags?" collection:"csv"`
+//	}
+//
+// The methods of a package are written to hr_bind.go in the directory
+// of the package, see the -o flag. Fields tagged with `path`, `query`,
+// `header`, `cookie` and `form` are bound by the methods, and fields of
+// types they can not bind, such as nested structs and maps, are reported
+// as errors, since such structs are better left to reflection. Bodies
+// decoded by codecs and uploaded files are bound by reflection.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const hrPath = "github.com/tekqer/hr"
+
+// tags are the tags of fields bound by generated methods, in the order
+// hr.Ctx.Bind binds them.
+var tags = []string{"path", "query", "header", "cookie", "form"}
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package")
+	out := flag.String("o", "hr_bind.go", "name of the file to write in the directory of the package")
+	flag.Parse()
+
+	src, err := generate(*dir, *out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hrgen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *out), src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "hrgen:", err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the source of the BindHR methods of the structs
+// annotated in the package in dir, ignoring the file out which holds the
+// methods generated before.
+func generate(dir, out string) ([]byte, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == out {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		// the package may not type check without the methods.
+		Error: func(error) {},
+	}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+
+	g := generator{pkg: pkg, imports: map[string]string{hrPath: "hr"}}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if !annotated(ts.Doc) && !(len(gd.Specs) == 1 && annotated(gd.Doc)) {
+					continue
+				}
+				if err := g.generate(ts.Name.Name); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if g.n == 0 {
+		return nil, errors.New("no struct annotated with //hr:bind in " + dir)
+	}
+	return g.source(bp.Name)
+}
+
+// annotated reports whether doc has a //hr:bind line.
+func annotated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == "//hr:bind" {
+			return true
+		}
+	}
+	return false
+}
+
+type generator struct {
+	pkg     *types.Package
+	imports map[string]string // names of the packages imported by path.
+	buf     bytes.Buffer
+	n       int // number of methods generated.
+}
+
+func (g *generator) source(name string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by hrgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", name)
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if name := g.imports[path]; name != pathpkg.Base(path) {
+			fmt.Fprintf(&buf, "\t%s %q\n", name, path)
+			continue
+		}
+		fmt.Fprintf(&buf, "\t%q\n", path)
+	}
+	buf.WriteString(")\n")
+	buf.Write(g.buf.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// qualifier qualifies types of other packages with their names, which
+// are imported by the generated file.
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	g.imports[pkg.Path()] = pkg.Name()
+	return pkg.Name()
+}
+
+// generate generates the BindHR method of the struct named name.
+func (g *generator) generate(name string) error {
+	obj := g.pkg.Scope().Lookup(name)
+	if obj == nil {
+		return fmt.Errorf("%s: type not found", name)
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return fmt.Errorf("%s: generic types and aliases are not supported", name)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("%s: not a struct", name)
+	}
+
+	fmt.Fprintf(&g.buf, "\n// BindHR binds the request to v like hr.Ctx.Bind does, without reflection.\n")
+	fmt.Fprintf(&g.buf, "func (v *%s) BindHR(c *hr.Ctx) error {\n", name)
+	fmt.Fprintf(&g.buf, "\treturn c.BindFunc(v, func(s *hr.Source) {\n\t\tswitch s.Tag() {\n")
+	for _, tag := range tags {
+		var stmts []string
+		if err := g.fields(&stmts, st, tag, "v", name); err != nil {
+			return err
+		}
+		if len(stmts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&g.buf, "\t\tcase %q:\n", tag)
+		for _, stmt := range stmts {
+			fmt.Fprintf(&g.buf, "\t\t\t%s\n", stmt)
+		}
+	}
+	g.buf.WriteString("\t\t}\n\t})\n}\n")
+	g.n++
+	return nil
+}
+
+// fields appends the statements binding the fields of st tagged with tag
+// to stmts, where sel selects st and path names it in errors. Fields are
+// walked like hr does by reflection.
+func (g *generator) fields(stmts *[]string, st *types.Struct, tag, sel, path string) error {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		stag := reflect.StructTag(st.Tag(i))
+		key := stag.Get(tag)
+		fsel, fpath := sel+"."+f.Name(), path+"."+f.Name()
+
+		if f.Embedded() && len(key) == 0 && !isType(f.Type()) {
+			switch t := f.Type().Underlying().(type) {
+			case *types.Struct:
+				if err := g.fields(stmts, t, tag, fsel, fpath); err != nil {
+					return err
+				}
+			case *types.Pointer:
+				if et, ok := t.Elem().Underlying().(*types.Struct); ok && f.Exported() && hasTag(et, tag) {
+					return fmt.Errorf("%s: embedded struct pointers are not supported", fpath)
+				}
+			}
+			continue
+		}
+		if !f.Exported() || len(key) == 0 {
+			continue
+		}
+
+		opt := strings.HasSuffix(key, "?")
+		key = strings.TrimSuffix(key, "?")
+		call, arg, err := g.binding(f.Type())
+		if err != nil {
+			return fmt.Errorf("%s: %w", fpath, err)
+		}
+		field := strings.TrimPrefix(fsel, "v.")
+		*stmts = append(*stmts, fmt.Sprintf("hr.%s(s, %s, %q, %q, %s)", call, fmt.Sprintf(arg, "&"+fsel), field, key, tagsLiteral(stag, opt)))
+	}
+	return nil
+}
+
+// binding returns the function binding fields of type t, and the format
+// of its argument which converts the address of a field if needed.
+func (g *generator) binding(t types.Type) (string, string, error) {
+	if !isLeaf(t) {
+		return "", "", fmt.Errorf("unsupported type %s", types.TypeString(t, g.qualifier))
+	}
+	if implementsByPointer(t) {
+		return "BindValue", "%s", nil
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		if _, ok := t.(*types.Named); ok {
+			return "BindSlice", "(*[]" + types.TypeString(u.Elem(), g.qualifier) + ")(%s)", nil
+		}
+		return "BindSlice", "%s", nil
+	case *types.Pointer:
+		if _, ok := t.(*types.Named); ok {
+			break
+		}
+		return "BindPointer", "%s", nil
+	case *types.Basic:
+		if u.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) == 0 {
+			break
+		}
+		if _, ok := t.(*types.Named); ok {
+			return "BindValue", "(*" + u.Name() + ")(%s)", nil
+		}
+		return "BindValue", "%s", nil
+	}
+	return "", "", fmt.Errorf("unsupported type %s", types.TypeString(t, g.qualifier))
+}
+
+// tagsLiteral returns the hr.Tags literal of the tags of a field.
+func tagsLiteral(tag reflect.StructTag, opt bool) string {
+	var elems []string
+	if opt {
+		elems = append(elems, "Optional: true")
+	}
+	if def, ok := tag.Lookup("default"); ok {
+		elems = append(elems, "Default: "+strconv.Quote(def), "HasDefault: true")
+	}
+	for _, t := range [][2]string{{"Mod", "mod"}, {"Layout", "layout"}, {"Collection", "collection"}} {
+		if v := tag.Get(t[1]); len(v) > 0 {
+			elems = append(elems, t[0]+": "+strconv.Quote(v))
+		}
+	}
+	return "hr.Tags{" + strings.Join(elems, ", ") + "}"
+}
+
+// hasTag reports whether any field of st or of the structs embedded in
+// st is tagged with tag.
+func hasTag(st *types.Struct, tag string) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if _, ok := reflect.StructTag(st.Tag(i)).Lookup(tag); ok {
+			return true
+		}
+		if et, ok := st.Field(i).Type().Underlying().(*types.Struct); ok && st.Field(i).Embedded() && hasTag(et, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMethod reports whether t has a method named name which takes a
+// parameter of type param and returns an error, like Parse of hr.Type.
+func hasMethod(t types.Type, name string, param types.Type) bool {
+	sel := types.NewMethodSet(t).Lookup(nil, name)
+	if sel == nil {
+		return false
+	}
+	sig := sel.Obj().Type().(*types.Signature)
+	return sig.Params().Len() == 1 && types.Identical(sig.Params().At(0).Type(), param) &&
+		sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+// implements reports whether t implements hr.Type or
+// encoding.TextUnmarshaler.
+func implements(t types.Type) bool {
+	return hasMethod(t, "Parse", types.Typ[types.String]) ||
+		hasMethod(t, "UnmarshalText", types.NewSlice(types.Typ[types.Byte]))
+}
+
+// implementsByPointer reports whether the pointer to t implements
+// hr.Type or encoding.TextUnmarshaler, in which case values of t are set
+// by the interfaces.
+func implementsByPointer(t types.Type) bool {
+	if _, ok := t.(*types.Pointer); ok {
+		return false
+	}
+	return implements(types.NewPointer(t))
+}
+
+// isType is like isType of hr.
+func isType(t types.Type) bool {
+	return implements(t) || implementsByPointer(t)
+}
+
+// isLeaf is like isLeaf of hr.
+func isLeaf(t types.Type) bool {
+	if isType(t) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct, *types.Map:
+		return false
+	case *types.Pointer:
+		return isLeaf(u.Elem())
+	case *types.Slice:
+		e := u.Elem()
+		_, slice := e.Underlying().(*types.Slice)
+		return isType(e) || !slice && isLeaf(e)
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tekqer/hr"
+	"github.com/tekqer/hr/cmd/hrgen/internal/example"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	got, err := generate(dir, "hr_bind.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "hr_bind.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("hr_bind.go is out of date, run go generate:\n%s", got)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := map[string]string{
+		"type T struct{ M map[string]string `query:\"m\"` }":             "T.M: unsupported type map[string]string",
+		"type T struct{ A struct{ B int `query:\"b\"` } `query:\"a\"` }": "T.A: unsupported type struct",
+		"type T struct{ *E }\ntype E struct{ B int `form:\"b\"` }":       "T.E: embedded struct pointers are not supported",
+		"type T struct{ C complex128 `path:\"c\"` }":                     "T.C: unsupported type complex128",
+		"type T int": "T: not a struct",
+		"type T struct{ S [][]int `query:\"s\"` }":                                "T.S: unsupported type [][]int",
+		"type U struct{ N int `query:\"n\"` }\nvar _ = U{}\ntype T = U\ntype V T": "no struct annotated",
+	}
+	for src, want := range cases {
+		dir := t.TempDir()
+		if !strings.HasPrefix(want, "no struct") {
+			src = strings.Replace(src, "type T", "//hr:bind\ntype T", 1)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "t.go"), []byte("package t\n\n"+src+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := generate(dir, "hr_bind.go"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: want error %q got %v", src, want, err)
+		}
+	}
+}
+
+// plainListOrders and plainCreateOrder are bound by reflection, since
+// they have the fields but not the methods of the generated ones.
+type (
+	plainListOrders  example.ListOrders
+	plainCreateOrder example.CreateOrder
+)
+
+var _ hr.Binder = (*example.ListOrders)(nil)
+
+func TestParity(t *testing.T) {
+	var got interface{}
+	var gotErr error
+	// requests with X-All are bound by BindAll.
+	bindAll := func(c *hr.Ctx, v interface{}) error {
+		if len(c.Request().Header.Get("X-All")) > 0 {
+			return c.BindAll(v)
+		}
+		return c.Bind(v)
+	}
+	r := hr.Default()
+	r.GET("/orgs/:org/orders", func(c *hr.Ctx) error {
+		if len(c.Request().Header.Get("X-Plain")) > 0 {
+			var p plainListOrders
+			gotErr = bindAll(c, &p)
+			got = example.ListOrders(p)
+			return nil
+		}
+		var g example.ListOrders
+		gotErr, got = bindAll(c, &g), g
+		return nil
+	})
+	r.POST("/orgs/:org/orders", func(c *hr.Ctx) error {
+		if len(c.Request().Header.Get("X-Plain")) > 0 {
+			var p plainCreateOrder
+			gotErr = bindAll(c, &p)
+			got = example.CreateOrder(p)
+			return nil
+		}
+		var g example.CreateOrder
+		gotErr, got = bindAll(c, &g), g
+		return nil
+	})
+	bind := func(method, target, ctype, body string, header map[string]string, plain bool) (interface{}, string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if len(ctype) > 0 {
+			req.Header.Set("Content-Type", ctype)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		if plain {
+			req.Header.Set("X-Plain", "1")
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return got, errorJSON(gotErr)
+	}
+
+	multipart := "--b\r\nContent-Disposition: form-data; name=\"sku\"\r\n\r\nA\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"receipt\"; filename=\"r.txt\"\r\n\r\npaid\r\n--b--\r\n"
+	all := map[string]string{"X-All": "1", "X-Tenant": "acme", "Cookie": "session=s"}
+
+	cases := []struct {
+		method, target, ctype, body string
+		header                      map[string]string
+	}{
+		{"GET", "/orgs/7/orders", "", "", nil},
+		{"GET", "/orgs/7/orders?page=2&size=&status=+Open+&ids=1,2,3&tags=a&tags=b&since=2023-06-05&until=2023-06-06" +
+			"&level=HIGH&levels=low|high&limit=10&count=3&ratio=0.5&exact=true&color=red&private=x", "", "", nil},
+		{"GET", "/orgs/x/orders?page=x&ids=1,x&since=bad&until=bad&level=mid&levels=low|mid&limit=70000" +
+			"&count=x&ratio=x&exact=x&color=pink", "", "", nil},
+		{"GET", "/orgs/7/orders?tags[]=a&tags[]=b&ids=&count=", "", "", nil},
+		{"POST", "/orgs/7/orders", "application/x-www-form-urlencoded",
+			"sku=A&qty=2&notes=x&notes=y&id=123e4567-e89b-12d3-a456-426614174000&when=2023-06-05T21:33:45Z" +
+				"&dates[0]=2023-06-05&dates[1]=2023-06-06", nil},
+		{"POST", "/orgs/7/orders?sku=Q", "application/x-www-form-urlencoded", "", nil},
+		{"POST", "/orgs/7/orders", "application/x-www-form-urlencoded", "notes=x", nil},
+		{"POST", "/orgs/7/orders", "application/x-www-form-urlencoded", "sku=A&qty=0", nil},
+		{"POST", "/orgs/7/orders", "application/x-www-form-urlencoded", "sku=A&qty=300&id=bad&when=bad&dates[x]=1&dates[1]=bad", nil},
+		{"POST", "/orgs/7/orders?sku=Q", "application/json", `{"SKU":"J","Qty":3}`, nil},
+		{"POST", "/orgs/7/orders", "application/json", `{"Qty":3}`, nil},
+		{"POST", "/orgs/7/orders", "multipart/form-data; boundary=b", multipart, nil},
+		{"GET", "/orgs/7/orders?page=2", "", "", all},
+		{"GET", "/orgs/7/orders", "", "", map[string]string{"X-All": "1"}},
+		{"POST", "/orgs/7/orders?sku=Q", "multipart/form-data; boundary=b", multipart, all},
+	}
+	for _, v := range cases {
+		gen, genErr := bind(v.method, v.target, v.ctype, v.body, v.header, false)
+		plain, plainErr := bind(v.method, v.target, v.ctype, v.body, v.header, true)
+		if !reflect.DeepEqual(gen, plain) || genErr != plainErr {
+			t.Fatalf("%s %s %s:\ngenerated  %+v %s\nreflection %+v %s", v.method, v.target, v.body, gen, genErr, plain, plainErr)
+		}
+	}
+
+	if gen, _ := bind("GET", "/orgs/7/orders", "", "", all, false); gen.(example.ListOrders).Tenant != "acme" {
+		t.Fatalf("want tenant bound got %+v", gen)
+	}
+	if gen, _ := bind("POST", "/orgs/7/orders", "multipart/form-data; boundary=b", multipart, nil, false); gen.(example.CreateOrder).Receipt == nil {
+		t.Fatalf("want receipt bound got %+v", gen)
+	}
+}
+
+func errorJSON(err error) string {
+	if err == nil {
+		return "null"
+	}
+	b, _ := json.Marshal(err)
+	return string(b)
+}
+
+func benchmarkBind(b *testing.B, v interface{}) {
+	r := hr.Default()
+	r.GET("/orgs/:org/orders", func(c *hr.Ctx) error {
+		return c.Bind(v)
+	})
+	req := httptest.NewRequest("GET", "/orgs/7/orders?page=2&status=open&ids=1,2,3&tags=a&tags=b&since=2023-06-05&level=high&limit=10", nil)
+	rw := httptest.NewRecorder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(rw, req)
+	}
+}
+
+func BenchmarkBindGenerated(b *testing.B) {
+	benchmarkBind(b, new(example.ListOrders))
+}
+
+func BenchmarkBindReflection(b *testing.B) {
+	benchmarkBind(b, new(plainListOrders))
+}
@@ -0,0 +1,185 @@
+package hr
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Codec encodes and decodes values in a media type. Codecs registered
+// on a Router are used by Ctx.Bind to decode request bodies, and by
+// Ctx.Encode and Ctx.Negotiate to encode responses.
+type Codec interface {
+	// MediaType returns the media type handled by the codec, such as
+	// "application/json".
+	MediaType() string
+	// Decode decodes a value from r and stores it in v, which must be
+	// a pointer.
+	Decode(r io.Reader, v interface{}) error
+	// Encode writes the encoding of v to w.
+	Encode(w io.Writer, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaType() string                       { return "application/json" }
+func (jsonCodec) Encode(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) }
+func (c jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return c.decode(r, v, &DecodeOptions{})
+}
+
+func (jsonCodec) decode(r io.Reader, v interface{}, opts *DecodeOptions) error {
+	var dr *jsonDepthReader
+	if opts.MaxDepth > 0 {
+		dr = &jsonDepthReader{r: r, max: opts.MaxDepth}
+		r = dr
+	}
+	dec := json.NewDecoder(r)
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(v); err != nil {
+		if dr != nil && dr.err != nil {
+			return dr.err
+		}
+		return err
+	}
+	if opts.DisallowTrailingData {
+		if _, err := dec.Token(); err != io.EOF {
+			if err == nil {
+				err = fmt.Errorf("json: %w", errTrailingData)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) MediaType() string                       { return "application/xml" }
+func (xmlCodec) Encode(w io.Writer, v interface{}) error { return xml.NewEncoder(w).Encode(v) }
+func (c xmlCodec) Decode(r io.Reader, v interface{}) error {
+	return c.decode(r, v, &DecodeOptions{})
+}
+
+func (xmlCodec) decode(r io.Reader, v interface{}, opts *DecodeOptions) error {
+	raw := xml.NewDecoder(r)
+	raw.CharsetReader = charsetReader
+	if _, ok := r.(utf8Reader); ok {
+		// the body has been transcoded already, so the encoding it
+		// declares must be ignored.
+		raw.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+	tr := &xmlTokenReader{dec: raw, max: opts.MaxDepth}
+	if err := xml.NewTokenDecoder(tr).Decode(v); err != nil {
+		return err
+	}
+	if opts.DisallowTrailingData {
+		return tr.trailing()
+	}
+	return nil
+}
+
+type gobCodec struct{}
+
+func (gobCodec) MediaType() string                       { return "application/gob" }
+func (gobCodec) Encode(w io.Writer, v interface{}) error { return gob.NewEncoder(w).Encode(v) }
+func (c gobCodec) Decode(r io.Reader, v interface{}) error {
+	return c.decode(r, v, &DecodeOptions{})
+}
+
+func (gobCodec) decode(r io.Reader, v interface{}, opts *DecodeOptions) error {
+	if opts.MaxDepth > 0 {
+		// gob streams can only be checked for depth by decoding them.
+		return UnsupportedMediaType("gob bodies are not accepted with a max depth")
+	}
+	// gob does not read ahead of a value from an io.ByteReader, so what
+	// follows the value is left in br.
+	br := bufio.NewReader(r)
+	if err := gob.NewDecoder(br).Decode(v); err != nil {
+		return err
+	}
+	if opts.DisallowTrailingData {
+		if _, err := br.ReadByte(); err != io.EOF {
+			return fmt.Errorf("gob: %w", errTrailingData)
+		}
+	}
+	return nil
+}
+
+// defaultCodecs are the codecs every router starts with, in the order
+// of the server preference for content negotiation.
+var defaultCodecs = []Codec{jsonCodec{}, xmlCodec{}, gobCodec{}, cborCodec{}}
+
+// Codec registers codec on the router. A codec registered for a media
+// type that is already handled replaces the former one, otherwise it is
+// the least preferred one in content negotiation.
+func (r *Router) Codec(codec Codec) {
+	mt := codec.MediaType()
+	for i, c := range r.codecs {
+		if c.MediaType() == mt {
+			r.codecs[i] = codec
+			return
+		}
+	}
+	r.codecs = append(r.codecs, codec)
+}
+
+// codecs returns the codecs available to c.
+func (c *Ctx) codecs() []Codec {
+	if c.router == nil {
+		return defaultCodecs
+	}
+	return c.router.codecs
+}
+
+// codec returns the codec for the media type mt, or nil if there is none.
+func (c *Ctx) codec(mt string) Codec {
+	for _, codec := range c.codecs() {
+		if codec.MediaType() == mt {
+			return codec
+		}
+	}
+	return nil
+}
+
+// codecFor returns the codec to decode the media type mt, or nil if
+// there is none. Media types with a structured syntax suffix such as
+// application/vnd.api+json fall back to the codec of the suffix, and
+// so do text types such as text/xml.
+func (c *Ctx) codecFor(mt string) Codec {
+	if codec := c.codec(mt); codec != nil {
+		return codec
+	}
+	typ, sub, _ := strings.Cut(mt, "/")
+	if i := strings.LastIndexByte(sub, '+'); i >= 0 {
+		return c.codec("application/" + sub[i+1:])
+	}
+	if typ == "text" {
+		return c.codec("application/" + sub)
+	}
+	return nil
+}
+
+// Encode sends v with the given status code, encoded by the codec
+// registered for the media type ctype.
+func (c *Ctx) Encode(code int, ctype string, v interface{}) error {
+	codec := c.codec(ctype)
+	if codec == nil {
+		return fmt.Errorf("no codec for media type %s", ctype)
+	}
+	if err := c.commit(code, ctype); err != nil {
+		return err
+	}
+	return codec.Encode(&c.rw, v)
+}
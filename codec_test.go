@@ -0,0 +1,71 @@
+package hr
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// upperCodec is a JSON codec that encodes strings in upper case.
+type upperCodec struct{}
+
+func (upperCodec) MediaType() string { return "application/json" }
+
+func (upperCodec) Decode(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) }
+
+func (upperCodec) Encode(w io.Writer, v interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, strings.ToUpper(buf.String()))
+	return err
+}
+
+func TestCodec(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	r := Default()
+	r.Codec(upperCodec{})
+	r.POST("/echo", func(c *Ctx) error {
+		var u user
+		if err := c.Bind(&u); err != nil {
+			return err
+		}
+		return c.Negotiate(http.StatusOK, &u)
+	})
+
+	var body bytes.Buffer
+	(cborCodec{}).Encode(&body, user{Name: "bob"})
+
+	cases := []struct {
+		ctype  string
+		body   io.Reader
+		accept string
+		code   int
+		want   string
+	}{
+		{"application/json", strings.NewReader(`{"name":"bob"}`), "", http.StatusOK, "{\"NAME\":\"BOB\"}\n"},
+		{"application/cbor", &body, "application/xml", http.StatusOK, "<user><Name>bob</Name></user>"},
+		{"text/plain", strings.NewReader("bob"), "", http.StatusUnsupportedMediaType, ""},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest("POST", "/echo", v.body)
+		req.Header.Set("Content-Type", v.ctype)
+		req.Header.Set("Accept", v.accept)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code {
+			t.Fatalf("[%s] bad status code, want %d got %d", v.ctype, v.code, rw.Code)
+		}
+		if len(v.want) > 0 && rw.Body.String() != v.want {
+			t.Fatalf("[%s] bad response body %q", v.ctype, rw.Body.String())
+		}
+	}
+}
@@ -2,42 +2,119 @@ package hr
 
 import (
 	"context"
-	"encoding/gob"
 	"encoding/json"
-	"encoding/xml"
-	"mime/multipart"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 type Ctx struct {
 	context.Context
-	req   *http.Request
-	rw    http.ResponseWriter
-	query url.Values
-	vars  Vars
+	router *Router
+	req    *http.Request
+	rw     response
+	query  url.Values
+	vars   Vars
+	store  []entry
 
-	wroteHeader bool
+	uploads  *UploadLimits
+	decoding *DecodeOptions
+	binding  func(s *Source) // binds values in place of reflection, see BindFunc.
+	all      bool            // whether BindFunc is binding for BindAll.
+}
+
+type entry struct {
+	key   string
+	value any
 }
 
 // Request returns the underlying HTTP request.
-func (c Ctx) Request() *http.Request {
+func (c *Ctx) Request() *http.Request {
 	return c.req
 }
 
-// ResponseWriter returns the underlying response writer.
-func (c Ctx) ResponseWriter() http.ResponseWriter {
-	return c.rw
+// ResponseWriter returns the response writer. Everything written
+// through it is tracked by Status, Size and Committed.
+func (c *Ctx) ResponseWriter() http.ResponseWriter {
+	return &c.rw
+}
+
+// Status returns the status code of the response, or 0 if the response
+// header has not been sent yet.
+func (c *Ctx) Status() int {
+	return c.rw.status
+}
+
+// Size returns the number of bytes of the response body written so far.
+func (c *Ctx) Size() int64 {
+	return c.rw.size
+}
+
+// Committed reports whether the response header has been sent. Once
+// committed, the status code and the header can no longer be changed.
+func (c *Ctx) Committed() bool {
+	return c.rw.committed
 }
 
 // Vars returns the named route variables
-func (c Ctx) Vars() Vars {
+func (c *Ctx) Vars() Vars {
 	return c.vars
 }
 
+// Set stores v under key for the lifetime of the request. It is the
+// way for plugins to hand data over to the handlers they wrap, and
+// everything stored is dropped once the request is served.
+func (c *Ctx) Set(key string, v any) {
+	for i := range c.store {
+		if c.store[i].key == key {
+			c.store[i].value = v
+			return
+		}
+	}
+	c.store = append(c.store, entry{key, v})
+}
+
+// Get returns the value stored under key, or nil if there is none.
+func (c *Ctx) Get(key string) any {
+	for _, e := range c.store {
+		if e.key == key {
+			return e.value
+		}
+	}
+	return nil
+}
+
+// Value returns the value stored in c under key as a T. It reports
+// false if there is no such value or the value is not a T.
+func Value[T any](c *Ctx, key string) (T, bool) {
+	v, ok := c.Get(key).(T)
+	return v, ok
+}
+
+// reset clears c so that it can be put back to the context pool.
+func (c *Ctx) reset() {
+	for i := range c.store {
+		c.store[i] = entry{} // do not hold values in the pool.
+	}
+	c.Context = nil
+	c.req = nil
+	c.rw.reset(nil)
+	c.query = nil
+	c.vars = nil
+	c.store = c.store[:0]
+	c.uploads = nil
+	c.decoding = nil
+}
+
 // Query parses the URL query string and returns the corresponding
 // values. It silently discards malformed value pairs.
-func (c Ctx) Query() url.Values {
+func (c *Ctx) Query() url.Values {
 	if c.query == nil {
 		c.query = c.req.URL.Query()
 	}
@@ -46,7 +123,7 @@ func (c Ctx) Query() url.Values {
 
 // Form returns the parsed form data including both the URL field's
 // query parameters and the PATCH, POST, or PUT form data.
-func (c Ctx) Form() (url.Values, error) {
+func (c *Ctx) Form() (url.Values, error) {
 	// we don't bother to check if the form is parsed or not
 	// because ParseForm will do it for us.
 	if err := c.req.ParseForm(); err != nil {
@@ -55,23 +132,27 @@ func (c Ctx) Form() (url.Values, error) {
 	return c.req.Form, nil
 }
 
-// MultipartForm returns parsed multipart form, including file uploads.
-func (c Ctx) MultipartForm() (*multipart.Form, error) {
-	// we don't bother to check if the form is parsed or not
-	// because ParseMultipartForm will do it for us.
-	if err := c.req.ParseMultipartForm(32 << 20); err != nil { // 32MiB
-		return nil, err
-	}
-	return c.req.MultipartForm, nil
-}
-
 // Bind deserializes data from the request to a Go struct. Where data
-// is extracted is specified by Content-Type. The URL query string if
-// presented is also deserialized to the struct if there are fields
-// tagged with `query`. Fields will be validated if they are tagged with
-// `validate`. Any error occurried during the call will be returned
-// after wrapped with an hr.Error that results in a response with a
-// 404 (bad request) status code. NOTE that v must be a pointer.
+// is extracted is specified by Content-Type: form data are bound to
+// fields tagged with `form`, files of multipart forms are bound to
+// fields of *multipart.FileHeader or []*multipart.FileHeader tagged with
+// `file` within the limits given by LimitUploads, and other request
+// bodies are decoded by the codec registered for the content type. Structured syntax suffixes
+// are understood, so application/vnd.api+json is decoded as JSON, and
+// bodies in charsets other than UTF-8 are transcoded before binding.
+// Content types that cannot be bound result in a 415 (unsupported media
+// type) error. The URL query string if presented is also deserialized
+// to the struct if there are fields tagged with `query`. Slice fields
+// are bound in the collection format given by their `collection` tag or
+// by Router.CollectionFormat, like ids=1,2 of csv. Request bodies are
+// decoded with the options given by Decoding. Fields will be
+// validated if they are tagged with `validate`, see Validate for the
+// rules. Any error occurried during the call will be returned after
+// wrapped with an hr.Error that results in a response with a
+// 400 (bad request) status code, which lists every field failed to bind
+// or to validate. If v implements Binder, like structs given BindHR
+// methods by cmd/hrgen, it binds itself instead. NOTE that v must be a
+// pointer.
 //
 // Example:
 //
@@ -87,59 +168,298 @@ func (c Ctx) MultipartForm() (*multipart.Form, error) {
 //	    }
 //	    // do something with u
 //	}
-func (c Ctx) Bind(v interface{}) error {
-	req := c.req
+func (c *Ctx) Bind(v interface{}) error {
+	if b, ok := v.(Binder); ok {
+		return b.BindHR(c)
+	}
+	var errs BindError
+	if err := c.bind(v, &errs, false); err != nil {
+		return bindError(err)
+	}
+	return c.validate(v, &errs)
+}
 
-	if err := c.BindPath(v); err != nil {
-		return err
+// BindAll is like Bind, but binds the request header and cookies as well
+// as the route variables, the query string and the body, then validates
+// v once. A field tagged for several sources is bound from the first one
+// giving it in the order of precedence:
+//
+//	path > query > header > cookie > body
+//
+// and it is missing only if none of them gives it. Note that bodies
+// decoded by codecs set fields regardless of their tags for the other
+// sources, so they are decoded first to be overridden by the others.
+// Like Bind, BindAll prefers the BindHR method of v if it is a Binder.
+//
+// Example:
+//
+//	type GetOrder struct {
+//	    ID      int    `path:"id"`
+//	    Expand  bool   `query:"expand?"`
+//	    Tenant  string `header:"X-Tenant"`
+//	    Session string `cookie:"session"`
+//	}
+func (c *Ctx) BindAll(v interface{}) error {
+	if b, ok := v.(Binder); ok {
+		c.all = true
+		defer func() { c.all = false }()
+		return b.BindHR(c)
 	}
+	var errs BindError
+	if err := c.bind(v, &errs, true); err != nil {
+		return bindError(err)
+	}
+	return c.validate(v, &errs)
+}
 
+// bind binds the request to v, collecting fields failed into errs. The
+// header and cookies are bound if all is true. Any other error is
+// returned.
+func (c *Ctx) bind(v interface{}, errs *BindError, all bool) error {
+	req := c.req
+	bound := make(map[string]bool)
+
+	var body, form bool
 	switch req.Method {
 	case http.MethodGet, http.MethodDelete, http.MethodHead:
-		if err := bind(v, req.URL.Query(), "query"); err != nil {
-			return BadRequest(err.Error())
+	default:
+		body = true
+		mt, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		form = mt == "application/x-www-form-urlencoded" || mt == "multipart/form-data"
+	}
+
+	if body && !form {
+		if err := errs.collect(c.bindBody(v, bound)); err != nil {
+			return err
 		}
+	}
+	if err := errs.collect(c.bindPath(v, bound)); err != nil {
+		return err
+	}
+	if err := errs.collect(c.bindValues(v, c.Query(), "query", bound)); err != nil {
+		return err
+	}
+	if all {
+		if err := errs.collect(c.bindValues(v, req.Header, "header", bound)); err != nil {
+			return err
+		}
+		if err := errs.collect(c.bindValues(v, c.cookieValues(), "cookie", bound)); err != nil {
+			return err
+		}
+	}
+	if body && form {
+		if err := errs.collect(c.bindBody(v, bound)); err != nil {
+			return err
+		}
+	}
+	var given func(path string) bool
+	if body && !form {
+		// Fields of bodies decoded by codecs are not tracked, but given
+		// if they are set in v.
+		given = func(path string) bool {
+			val, ok := valueAt(reflect.ValueOf(v), path)
+			return ok && hasValue(val)
+		}
+	}
+	errs.resolve(bound, given)
+	return nil
+}
+
+// valueAt returns the value of the field at path in the struct val points
+// to, and false if it is behind a nil pointer or out of range.
+func valueAt(val reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		switch val = indirect(val); val.Kind() {
+		case reflect.Struct:
+			val = val.FieldByName(name)
+		case reflect.Slice:
+			i, err := strconv.Atoi(name)
+			if err != nil || i >= val.Len() {
+				return val, false
+			}
+			val = val.Index(i)
+		default:
+			return val, false
+		}
+		if !val.IsValid() {
+			return val, false
+		}
+	}
+	return val, true
+}
+
+// validate validates v and returns an hr.Error listing both the fields
+// failed to bind in errs and those invalid, if any.
+func (c *Ctx) validate(v interface{}, errs *BindError) error {
+	if err := errs.collect(validate(v, c.router)); err != nil {
+		return bindError(err)
+	}
+	return bindError(errs.err())
+}
+
+// bindError wraps an error occurred during binding with an hr.Error
+// resulting in a 400 (bad request) response, unless it is an hr.Error.
+func bindError(err error) error {
+	switch err := err.(type) {
+	case nil:
 		return nil
+	case Error:
+		return err
+	case *BindError:
+		return err.HTTPError()
 	}
+	return BadRequest(err.Error())
+}
 
+// bindBody binds the request body to v according to its content type.
+// Fields of forms already in bound are skipped.
+func (c *Ctx) bindBody(v interface{}, bound map[string]bool) error {
+	req := c.req
 	ctype := req.Header.Get("Content-Type")
-	switch ctype {
-	case "application/json":
-		defer req.Body.Close()
-		if err := json.NewDecoder(req.Body).Decode(v); err != nil {
-			return BadRequest(err.Error())
+	if len(ctype) == 0 && (req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0) {
+		// nothing to bind.
+		return nil
+	}
+
+	mt, params, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return c.unsupportedMediaType(ctype)
+	}
+	dec, err := charsetDecoder(params["charset"])
+	if err != nil {
+		return UnsupportedMediaType(err.Error())
+	}
+	opts := c.decoding
+	if opts == nil {
+		opts = &DecodeOptions{}
+	}
+	if opts.MaxBodySize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(&c.rw, req.Body, opts.MaxBodySize)
+	}
+
+	if mt == "application/x-www-form-urlencoded" {
+		if err := req.ParseForm(); err != nil {
+			return bodyError(err)
 		}
-	case "application/xml":
-		defer req.Body.Close()
-		if err := xml.NewDecoder(req.Body).Decode(v); err != nil {
-			return BadRequest(err.Error())
+		form := req.Form
+		if dec != nil {
+			// only the body is in the charset, query strings are always
+			// in UTF-8.
+			if form, err = transcodeForm(req.PostForm, dec); err != nil {
+				return BadRequest(err.Error())
+			}
+			for k, vs := range req.URL.Query() {
+				form[k] = append(form[k], vs...)
+			}
 		}
-	case "application/gob":
-		defer req.Body.Close()
-		if err := gob.NewDecoder(req.Body).Decode(v); err != nil {
-			return BadRequest(err.Error())
+		return c.bindValues(v, form, "form", bound)
+	}
+	if mt == "multipart/form-data" {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return err
 		}
-	case "application/x-www-form-urlencoded":
-		if err := req.ParseForm(); err != nil {
-			return BadRequest(err.Error())
+		var errs BindError
+		// req.Form holds the query string as well as the text fields.
+		if err := errs.collect(c.bindValues(v, req.Form, "form", bound)); err != nil {
+			return err
 		}
-		if err := bind(v, req.Form, "form"); err != nil {
-			return BadRequest(err.Error())
+		if err := errs.collect(bindFiles(v, form.File)); err != nil {
+			return err
 		}
-	default:
-		return BadRequest("bad content type")
+		return errs.err()
+	}
+
+	codec := c.codecFor(mt)
+	if codec == nil {
+		return c.unsupportedMediaType(mt)
+	}
+	defer req.Body.Close()
+	var body io.Reader = req.Body
+	if dec != nil {
+		body = utf8Reader{dec(body)}
+	}
+	if d, ok := codec.(optionsDecoder); ok {
+		err = d.decode(body, v, opts)
+	} else {
+		err = codec.Decode(body, v)
+	}
+	if err != nil {
+		var terr *json.UnmarshalTypeError
+		if errors.As(err, &terr) && len(terr.Field) > 0 {
+			return &BindError{Fields: []FieldError{{
+				Source:  "body",
+				Key:     terr.Field,
+				Message: "invalid value",
+				err:     err,
+			}}}
+		}
+		return bodyError(err)
 	}
 	return nil
 }
 
-func (c Ctx) BindHeader(v interface{}) error {
-	if err := bind(v, c.req.Header, "header"); err != nil {
-		return BadRequest(err.Error())
+// bodyError returns the hr.Error of an error reading the request body,
+// which results in a 413 (request entity too large) response if the body
+// is larger than allowed, or a 400 (bad request) one otherwise, unless it
+// is an hr.Error.
+func bodyError(err error) Error {
+	if e, ok := err.(Error); ok {
+		return e
 	}
-	return nil
+	var merr *http.MaxBytesError
+	if errors.As(err, &merr) {
+		return RequestEntityTooLarge("request body too large, at most %d bytes", merr.Limit)
+	}
+	return BadRequest(err.Error())
 }
 
-func (c Ctx) BindPath(v interface{}) error {
+// unsupportedMediaType returns an hr.Error resulting in a 415 (unsupported
+// media type) response which lists the media types that can be bound.
+func (c *Ctx) unsupportedMediaType(ctype string) Error {
+	codecs := c.codecs()
+	accepted := make([]string, 0, len(codecs)+2)
+	accepted = append(accepted, "application/x-www-form-urlencoded", "multipart/form-data")
+	for _, codec := range codecs {
+		accepted = append(accepted, codec.MediaType())
+	}
+	return UnsupportedMediaType("unsupported media type %q, accepted: %s", ctype, strings.Join(accepted, ", "))
+}
+
+// bindValues binds values to v in the collection format of the router.
+// Fields in bound are skipped if bound is not nil.
+func (c *Ctx) bindValues(v interface{}, values map[string][]string, tag string, bound map[string]bool) error {
+	b := binder{tag: tag, bound: bound}
+	if c.router != nil {
+		b.collection = c.router.collection
+	}
+	if c.binding != nil {
+		return b.bindFunc(values, c.binding)
+	}
+	return b.bind(v, values)
+}
+
+// BindHeader binds the request header to the fields of v tagged with
+// `header` and validates v. See also Bind.
+func (c *Ctx) BindHeader(v interface{}) error {
+	var errs BindError
+	if err := errs.collect(c.bindValues(v, c.req.Header, "header", nil)); err != nil {
+		return bindError(err)
+	}
+	return c.validate(v, &errs)
+}
+
+// BindPath binds the route variables to the fields of v tagged with
+// `path` and validates v. See also Bind.
+func (c *Ctx) BindPath(v interface{}) error {
+	var errs BindError
+	if err := errs.collect(c.bindPath(v, nil)); err != nil {
+		return bindError(err)
+	}
+	return c.validate(v, &errs)
+}
+
+func (c *Ctx) bindPath(v interface{}, bound map[string]bool) error {
 	vars := c.vars
 	if len(vars) == 0 {
 		return nil
@@ -148,51 +468,122 @@ func (c Ctx) BindPath(v interface{}) error {
 	for _, v := range vars {
 		vals[v.Key] = []string{v.Value}
 	}
-	if err := bind(v, vals, "path"); err != nil {
-		return BadRequest(err.Error())
-	}
-	return nil
+	return c.bindValues(v, vals, "path", bound)
 }
 
 // WriteHeader sends an HTTP response header with the provided status
 // code and should not be called more than once. Invocation after the
 // first one takes no effect.
-func (c Ctx) WriteHeader(code int) {
-	if !c.wroteHeader {
-		c.rw.WriteHeader(code)
-	}
+func (c *Ctx) WriteHeader(code int) {
+	c.rw.WriteHeader(code)
 }
 
-func (c Ctx) Write(p []byte) (int, error) {
+func (c *Ctx) Write(p []byte) (int, error) {
 	return c.rw.Write(p)
 }
 
 // NoContent sends an empty response body with status code 204 (no content).
-func (c Ctx) NoContent() error {
-	c.rw.WriteHeader(http.StatusNoContent)
-	return nil
+func (c *Ctx) NoContent() error {
+	return c.commit(http.StatusNoContent, "")
 }
 
 // JSON sends a JSON-encoded response body with status code 200.
-func (c Ctx) JSON(v interface{}) error {
-	w := c.rw
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	return json.NewEncoder(w).Encode(v)
+func (c *Ctx) JSON(v interface{}) error {
+	return c.JSONStatus(http.StatusOK, v)
+}
+
+// JSONStatus sends a JSON-encoded response body with the given status code.
+func (c *Ctx) JSONStatus(code int, v interface{}) error {
+	return c.Encode(code, "application/json", v)
 }
 
 // XML sends a XML-encoded response body with status code 200.
-func (c Ctx) XML(v interface{}) error {
-	w := c.rw
-	w.Header().Set("Content-Type", "application/xml")
-	w.WriteHeader(http.StatusOK)
-	return xml.NewEncoder(w).Encode(v)
+func (c *Ctx) XML(v interface{}) error {
+	return c.XMLStatus(http.StatusOK, v)
+}
+
+// XMLStatus sends a XML-encoded response body with the given status code.
+func (c *Ctx) XMLStatus(code int, v interface{}) error {
+	return c.Encode(code, "application/xml", v)
 }
 
 // Gob sends a Gob-encoded response body with status code 200.
-func (c Ctx) Gob(v interface{}) error {
-	w := c.rw
-	w.Header().Set("Content-Type", "application/gob")
-	w.WriteHeader(http.StatusOK)
-	return gob.NewEncoder(w).Encode(v)
+func (c *Ctx) Gob(v interface{}) error {
+	return c.GobStatus(http.StatusOK, v)
+}
+
+// GobStatus sends a Gob-encoded response body with the given status code.
+func (c *Ctx) GobStatus(code int, v interface{}) error {
+	return c.Encode(code, "application/gob", v)
+}
+
+// Created sends a response with status code 201 (created) and the
+// Location header set to the URL of the newly created resource. v if
+// not nil is sent as a JSON-encoded response body.
+func (c *Ctx) Created(location string, v interface{}) error {
+	if c.rw.committed {
+		return ErrCommitted
+	}
+	c.rw.Header().Set("Location", location)
+	if v == nil {
+		return c.commit(http.StatusCreated, "")
+	}
+	return c.JSONStatus(http.StatusCreated, v)
+}
+
+// Accepted sends a response with status code 202 (accepted), telling
+// the client that the request will be processed later. v if not nil is
+// sent as a JSON-encoded response body.
+func (c *Ctx) Accepted(v interface{}) error {
+	if v == nil {
+		return c.commit(http.StatusAccepted, "")
+	}
+	return c.JSONStatus(http.StatusAccepted, v)
+}
+
+// Blob sends b as the response body with the given status code and
+// content type.
+func (c *Ctx) Blob(code int, ctype string, b []byte) error {
+	if err := c.commit(code, ctype); err != nil {
+		return err
+	}
+	_, err := c.rw.Write(b)
+	return err
+}
+
+// String sends a formatted plain text response body with the given
+// status code.
+func (c *Ctx) String(code int, format string, v ...interface{}) error {
+	if err := c.commit(code, "text/plain; charset=utf-8"); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(&c.rw, format, v...)
+	return err
+}
+
+// Redirect replies to the request with a redirect to url, which may be
+// a path relative to the request path. code must be in the 3xx range.
+func (c *Ctx) Redirect(code int, url string) error {
+	if code < 300 || code > 399 {
+		return fmt.Errorf("bad redirect status code %d", code)
+	}
+	if c.rw.committed {
+		return ErrCommitted
+	}
+	http.Redirect(&c.rw, c.req, url, code)
+	return nil
+}
+
+// commit sends the response header with the given status code and
+// content type if ctype is not empty. It fails with ErrCommitted if the
+// response header has been sent.
+func (c *Ctx) commit(code int, ctype string) error {
+	if c.rw.committed {
+		return ErrCommitted
+	}
+	if len(ctype) > 0 {
+		c.rw.Header().Set("Content-Type", ctype)
+	}
+	c.rw.WriteHeader(code)
+	return nil
 }
@@ -0,0 +1,424 @@
+package hr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCtxValues(t *testing.T) {
+	setUser := func(next Handler) Handler {
+		return HandlerFunc(func(c *Ctx) error {
+			c.Set("user", "bob")
+			c.Set("id", 42)
+			c.Set("id", 43)
+			return next.ServeHTTP(c)
+		})
+	}
+
+	r := Default()
+	r.GET("/user", func(c *Ctx) error {
+		if user, ok := Value[string](c, "user"); !ok || user != "bob" {
+			t.Fatalf("bad user %q", user)
+		}
+		if id, ok := Value[int](c, "id"); !ok || id != 43 {
+			t.Fatalf("bad id %d", id)
+		}
+		if _, ok := Value[int](c, "user"); ok {
+			t.Fatal("user must not be an int")
+		}
+		return nil
+	}, setUser)
+	r.GET("/anon", func(c *Ctx) error {
+		if c.Get("user") != nil {
+			t.Fatal("values leaked from the previous request")
+		}
+		return nil
+	})
+
+	for _, path := range []string{"/user", "/anon"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != http.StatusOK {
+			t.Fatalf("[%s] bad status code %d", path, rw.Code)
+		}
+	}
+}
+
+func TestCtxResponseState(t *testing.T) {
+	r := Default()
+	r.GET("/partial", func(c *Ctx) error {
+		if c.Committed() || c.Status() != 0 {
+			t.Fatal("response must not be committed yet")
+		}
+		c.WriteHeader(http.StatusAccepted)
+		c.WriteHeader(http.StatusTeapot) // takes no effect
+		c.Write([]byte("hello"))
+		if !c.Committed() || c.Status() != http.StatusAccepted || c.Size() != 5 {
+			t.Fatalf("bad response state %d %d", c.Status(), c.Size())
+		}
+		return InternalServerError("too late")
+	})
+	r.GET("/error", func(c *Ctx) error {
+		return NotFound("nothing")
+	})
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/partial", http.StatusAccepted, "hello"},
+		{"/error", http.StatusNotFound, `{"code":404,"detail":"nothing"}`},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code {
+			t.Fatalf("[%s] bad status code, want %d got %d", v.path, v.code, rw.Code)
+		}
+		if rw.Body.String() != v.body {
+			t.Fatalf("[%s] bad response body %q", v.path, rw.Body.String())
+		}
+	}
+}
+
+func TestCtxResponseHelpers(t *testing.T) {
+	r := Default()
+	r.POST("/created", func(c *Ctx) error {
+		return c.Created("/created/1", map[string]int{"id": 1})
+	})
+	r.GET("/string", func(c *Ctx) error {
+		return c.String(http.StatusTeapot, "%d is the answer", 42)
+	})
+	r.GET("/blob", func(c *Ctx) error {
+		return c.Blob(http.StatusOK, "image/png", []byte{0x89, 'P', 'N', 'G'})
+	})
+	r.GET("/redirect", func(c *Ctx) error {
+		return c.Redirect(http.StatusFound, "/string")
+	})
+	r.GET("/twice", func(c *Ctx) error {
+		c.NoContent()
+		if err := c.JSONStatus(http.StatusOK, 1); err != ErrCommitted {
+			t.Fatalf("want ErrCommitted got %v", err)
+		}
+		if err := c.Created("/created/1", nil); err != ErrCommitted || len(c.rw.Header().Get("Location")) > 0 {
+			t.Fatalf("want ErrCommitted without Location got %v", err)
+		}
+		if err := c.Redirect(http.StatusOK, "/string"); err == nil || err == ErrCommitted {
+			t.Fatalf("want bad redirect status code got %v", err)
+		}
+		return nil
+	})
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+		header string
+		value  string
+		body   string
+	}{
+		{"POST", "/created", http.StatusCreated, "Location", "/created/1", "{\"id\":1}\n"},
+		{"GET", "/string", http.StatusTeapot, "Content-Type", "text/plain; charset=utf-8", "42 is the answer"},
+		{"GET", "/blob", http.StatusOK, "Content-Type", "image/png", "\x89PNG"},
+		{"GET", "/redirect", http.StatusFound, "Location", "/string", ""},
+		{"GET", "/twice", http.StatusNoContent, "Content-Type", "", ""},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code {
+			t.Fatalf("[%s] bad status code, want %d got %d", v.path, v.code, rw.Code)
+		}
+		if got := rw.Header().Get(v.header); got != v.value {
+			t.Fatalf("[%s] bad header %s %q", v.path, v.header, got)
+		}
+		if len(v.body) > 0 && rw.Body.String() != v.body {
+			t.Fatalf("[%s] bad response body %q", v.path, rw.Body.String())
+		}
+	}
+}
+
+func TestBindMediaType(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name" form:"name?"`
+	}
+
+	var got user
+	r := Default()
+	r.POST("/", func(c *Ctx) error {
+		got = user{}
+		return c.Bind(&got)
+	})
+
+	cases := []struct {
+		ctype string
+		body  string
+		code  int
+		name  string
+	}{
+		{"application/json; charset=utf-8", `{"name":"bob"}`, http.StatusOK, "bob"},
+		{"application/vnd.api+json", `{"name":"bob"}`, http.StatusOK, "bob"},
+		{"text/xml", `<user><name>bob</name></user>`, http.StatusOK, "bob"},
+		{"application/xml; charset=ISO-8859-1", "<user><name>J\xfcrgen</name></user>", http.StatusOK, "Jürgen"},
+		{"application/xml", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><user><name>J\xfcrgen</name></user>", http.StatusOK, "Jürgen"},
+		{"application/xml; charset=windows-1252", "<?xml version=\"1.0\" encoding=\"UTF-8\"?><user><name>\x80</name></user>", http.StatusOK, "€"},
+		{"application/x-www-form-urlencoded; charset=latin1", "name=J%FCrgen", http.StatusOK, "Jürgen"},
+		{"", "", http.StatusOK, ""},
+		{"text/plain", "bob", http.StatusUnsupportedMediaType, ""},
+		{"application/json; charset=koi8-r", `{"name":"bob"}`, http.StatusUnsupportedMediaType, ""},
+		{"application/json; charset", `{"name":"bob"}`, http.StatusUnsupportedMediaType, ""},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(v.body))
+		if len(v.ctype) > 0 {
+			req.Header.Set("Content-Type", v.ctype)
+		}
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code {
+			t.Fatalf("[%s] bad status code, want %d got %d: %s", v.ctype, v.code, rw.Code, rw.Body)
+		}
+		if v.code == http.StatusOK && got.Name != v.name {
+			t.Fatalf("[%s] bad name %q", v.ctype, got.Name)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	type order struct {
+		ID    int    `path:"id"`
+		Limit int    `query:"limit"`
+		Name  string `json:"name" validate:"required"`
+		Qty   int    `json:"qty" validate:"min=1"`
+	}
+
+	r := Default()
+	r.PUT("/orders/:id", func(c *Ctx) error {
+		var o order
+		return c.Bind(&o)
+	})
+
+	req, _ := http.NewRequest("PUT", "/orders/x", strings.NewReader(`{"qty":0}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("bad status code %d", rw.Code)
+	}
+
+	var e Error
+	if err := json.NewDecoder(rw.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldError{
+		{Source: "path", Key: "id", Message: "invalid value"},
+		{Source: "query", Key: "limit", Message: "missing field"},
+		{Source: "body", Key: "name", Rule: "required", Message: "invalid field"},
+		{Source: "body", Key: "qty", Rule: "min=1", Message: "invalid field"},
+	}
+	if !reflect.DeepEqual(e.Fields(), want) {
+		t.Fatalf("want %+v\ngot  %+v", want, e.Fields())
+	}
+	// errors with fields are comparable, like the others.
+	if errs := map[error]bool{e: true, NotFound("x"): true}; !errs[NotFound("x")] || !errs[e] {
+		t.Fatal("want errors comparable")
+	}
+
+	req, _ = http.NewRequest("PUT", "/orders/1", strings.NewReader(`{"name":"a","qty":"many"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if !strings.Contains(rw.Body.String(), `"source":"body","key":"qty","message":"invalid value"`) {
+		t.Fatalf("bad response body %s", rw.Body)
+	}
+}
+
+func TestBindAll(t *testing.T) {
+	type request struct {
+		ID      int      `path:"id" json:"id"`
+		Page    int      `query:"page" header:"X-Page"`
+		Tenant  string   `header:"X-Tenant" cookie:"tenant"`
+		Session string   `cookie:"session"`
+		Name    string   `json:"name" form:"name" validate:"required"`
+		Tags    []string `query:"tags?" form:"tags"`
+	}
+
+	var got request
+	r := Default()
+	r.POST("/items/:id", func(c *Ctx) error {
+		got = request{}
+		return c.BindAll(&got)
+	})
+
+	cases := []struct {
+		ctype, body, query string
+		header, cookie     string
+		code               int
+		want               request
+	}{
+		{
+			"application/json", `{"id":2,"name":"a"}`, "page=3", "", "session=s; tenant=t",
+			http.StatusOK, request{ID: 1, Page: 3, Tenant: "t", Session: "s", Name: "a"},
+		},
+		{
+			"application/x-www-form-urlencoded", "name=b&tags=y", "tags=x", "9", "session=s; tenant=t",
+			http.StatusOK, request{ID: 1, Page: 9, Tenant: "tenant", Session: "s", Name: "b", Tags: []string{"x"}},
+		},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest("POST", "/items/1?"+v.query, strings.NewReader(v.body))
+		req.Header.Set("Content-Type", v.ctype)
+		req.Header.Set("Cookie", v.cookie)
+		if len(v.header) > 0 {
+			req.Header.Set("X-Page", v.header)
+			req.Header.Set("X-Tenant", "tenant")
+		}
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code || !reflect.DeepEqual(got, v.want) {
+			t.Fatalf("bad response %d %s\nwant %+v\ngot  %+v", rw.Code, rw.Body, v.want, got)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/items/1", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	var e Error
+	json.NewDecoder(rw.Body).Decode(&e)
+	want := []FieldError{
+		{Source: "query", Key: "page", Message: "missing field"},
+		{Source: "header", Key: "X-Page", Message: "missing field"},
+		{Source: "header", Key: "X-Tenant", Message: "missing field"},
+		{Source: "cookie", Key: "tenant", Message: "missing field"},
+		{Source: "cookie", Key: "session", Message: "missing field"},
+		{Source: "body", Key: "name", Rule: "required", Message: "invalid field"},
+	}
+	if rw.Code != http.StatusBadRequest || !reflect.DeepEqual(e.Fields(), want) {
+		t.Fatalf("bad response %d\nwant %+v\ngot  %+v", rw.Code, want, e.Fields())
+	}
+
+	// the same keys of different sources fail apart.
+	r.GET("/keys", func(c *Ctx) error {
+		var v struct {
+			Q int `query:"x"`
+			H int `header:"x"`
+		}
+		return c.BindAll(&v)
+	})
+	req, _ = http.NewRequest("GET", "/keys?x=a", nil)
+	req.Header.Set("x", "b")
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	e = Error{}
+	json.NewDecoder(rw.Body).Decode(&e)
+	if len(e.Fields()) != 2 || e.Fields()[0].Source != "query" || e.Fields()[1].Source != "header" {
+		t.Fatalf("want errors of query and header got %+v", e.Fields())
+	}
+
+	// fields missing from a source are given by bodies and other sources,
+	// including those of embedded struct pointers.
+	type Paging struct {
+		Page int `query:"page" header:"X-Page"`
+	}
+	type given struct {
+		*Paging
+		Name string `json:"name" query:"name"`
+	}
+	var g given
+	r.POST("/given", func(c *Ctx) error {
+		g = given{}
+		return c.BindAll(&g)
+	})
+	req, _ = http.NewRequest("POST", "/given", strings.NewReader(`{"name":"a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Page", "2")
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK || g.Name != "a" || g.Paging == nil || g.Page != 2 {
+		t.Fatalf("bad response %d %s %+v", rw.Code, rw.Body, g)
+	}
+}
+
+type selfBound struct {
+	N    int      `query:"n"`
+	Tags []string `query:"tags?"`
+}
+
+func (s *selfBound) BindHR(c *Ctx) error {
+	return c.BindFunc(s, func(src *Source) {
+		if src.Tag() == "query" {
+			BindValue(src, &s.N, "N", "n", Tags{Default: "1", HasDefault: true})
+			BindSlice(src, &s.Tags, "Tags", "tags", Tags{Optional: true, Collection: "csv"})
+		}
+	})
+}
+
+func TestBinder(t *testing.T) {
+	var got selfBound
+	r := Default()
+	r.GET("/", func(c *Ctx) error {
+		got = selfBound{}
+		return c.Bind(&got)
+	})
+
+	cases := []struct {
+		query string
+		code  int
+		want  selfBound
+	}{
+		{"", http.StatusOK, selfBound{N: 1}},
+		{"n=5&tags=a,b", http.StatusOK, selfBound{N: 5, Tags: []string{"a", "b"}}},
+		{"n=x", http.StatusBadRequest, selfBound{}},
+	}
+	for _, v := range cases {
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, httptest.NewRequest("GET", "/?"+v.query, nil))
+		if rw.Code != v.code || !reflect.DeepEqual(got, v.want) {
+			t.Fatalf("%s: want %d %+v got %d %+v", v.query, v.code, v.want, rw.Code, got)
+		}
+	}
+
+	// bad tags fail the request rather than panicking.
+	r.GET("/bad", func(c *Ctx) error {
+		var s string
+		return c.BindFunc(&s, func(src *Source) {
+			BindValue(src, &s, "S", "s", Tags{Mod: "shout"})
+		})
+	})
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest("GET", "/bad?s=a", nil))
+	if rw.Code != http.StatusInternalServerError || !strings.Contains(rw.Body.String(), "unknown modifier") {
+		t.Fatalf("want 500 unknown modifier got %d %s", rw.Code, rw.Body)
+	}
+	r.GET("/layout", func(c *Ctx) error {
+		var n int
+		return c.BindFunc(&n, func(src *Source) {
+			BindValue(src, &n, "N", "n", Tags{Layout: "2006"})
+		})
+	})
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest("GET", "/layout?n=2023", nil))
+	if rw.Code != http.StatusInternalServerError || !strings.Contains(rw.Body.String(), "layout tag") {
+		t.Fatalf("want 500 layout tag got %d %s", rw.Code, rw.Body)
+	}
+	r.GET("/collection", func(c *Ctx) error {
+		var ids []int
+		return c.BindFunc(&ids, func(src *Source) {
+			BindSlice(src, &ids, "IDs", "ids", Tags{Collection: "tsv"})
+		})
+	})
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest("GET", "/collection?ids=1", nil))
+	if rw.Code != http.StatusInternalServerError || !strings.Contains(rw.Body.String(), "unknown collection format") {
+		t.Fatalf("want 500 unknown collection format got %d %s", rw.Code, rw.Body)
+	}
+}
@@ -0,0 +1,311 @@
+package hr
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CookieOptions are the attributes of a cookie set by Ctx.SetCookie.
+// Their zero values are secure defaults.
+type CookieOptions struct {
+	// Path defaults to "/".
+	Path   string
+	Domain string
+	// MaxAge is how long the cookie lives. The cookie is a session
+	// cookie if MaxAge is zero, and is deleted if MaxAge is negative.
+	MaxAge time.Duration
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+	// Insecure allows the cookie to be sent over unencrypted HTTP. Cookies
+	// are marked Secure by default.
+	Insecure bool
+	// Scriptable allows the cookie to be read by client-side scripts.
+	// Cookies are marked HttpOnly by default.
+	Scriptable bool
+}
+
+// BindCookie binds the request cookies to the fields of v tagged with
+// `cookie` and validates v. See also Bind.
+func (c *Ctx) BindCookie(v interface{}) error {
+	var errs BindError
+	if err := errs.collect(c.bindValues(v, c.cookieValues(), "cookie", nil)); err != nil {
+		return bindError(err)
+	}
+	return c.validate(v, &errs)
+}
+
+func (c *Ctx) cookieValues() map[string][]string {
+	cookies := c.req.Cookies()
+	vals := make(map[string][]string, len(cookies))
+	for _, cookie := range cookies {
+		vals[cookie.Name] = append(vals[cookie.Name], cookie.Value)
+	}
+	return vals
+}
+
+// Cookie returns the value of the request cookie named name. It returns
+// http.ErrNoCookie if there is no such cookie.
+func (c *Ctx) Cookie(name string) (string, error) {
+	cookie, err := c.req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+// SetCookie adds a Set-Cookie header to the response. opts may be nil,
+// in which case the cookie is a session cookie with secure defaults, see
+// CookieOptions. It fails if the cookie is invalid, or the response
+// header has been sent.
+func (c *Ctx) SetCookie(name, value string, opts *CookieOptions) error {
+	if opts == nil {
+		opts = &CookieOptions{}
+	}
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		SameSite: opts.SameSite,
+		Secure:   !opts.Insecure,
+		HttpOnly: !opts.Scriptable,
+	}
+	if len(cookie.Path) == 0 {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	switch {
+	case opts.MaxAge > 0:
+		cookie.MaxAge = int(opts.MaxAge / time.Second)
+		cookie.Expires = time.Now().Add(opts.MaxAge).UTC()
+	case opts.MaxAge < 0:
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(1, 0).UTC()
+	}
+
+	if err := cookie.Valid(); err != nil {
+		return err
+	}
+	if c.rw.committed {
+		return ErrCommitted
+	}
+	c.rw.Header().Add("Set-Cookie", cookie.String())
+	return nil
+}
+
+// ClearCookie tells the client to delete the cookie named name whose
+// path is "/". Use SetCookie with a negative MaxAge to delete cookies
+// of other paths or domains.
+func (c *Ctx) ClearCookie(name string) error {
+	return c.SetCookie(name, "", &CookieOptions{MaxAge: -1})
+}
+
+// ErrInvalidCookie is returned when a signed or encrypted cookie fails to
+// be verified or decrypted by any of the cookie keys, or has expired.
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+var errNoCookieKeys = errors.New("no cookie keys")
+
+// cookieKey is a key derived from a cookie key given to the router for
+// signing and encryption respectively.
+type cookieKey struct {
+	sign []byte
+	aead cipher.AEAD
+}
+
+func newCookieKey(key []byte) cookieKey {
+	derive := func(purpose string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(purpose))
+		return mac.Sum(nil)
+	}
+	block, err := aes.NewCipher(derive("hr cookie encryption"))
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return cookieKey{sign: derive("hr cookie signing"), aead: aead}
+}
+
+// CookieKeys sets the keys signed and encrypted cookies are protected
+// with. active is used to sign and encrypt cookies, while it and the
+// rest keys are used to verify and decrypt them, so that keys can be
+// rotated without invalidating the cookies issued before. Every key must
+// be at least 32 bytes long and kept secret.
+func (r *Router) CookieKeys(active []byte, rest ...[]byte) {
+	keys := make([]cookieKey, 0, len(rest)+1)
+	for _, key := range append([][]byte{active}, rest...) {
+		if len(key) < 32 {
+			panic("hr: cookie keys must be at least 32 bytes long")
+		}
+		keys = append(keys, newCookieKey(key))
+	}
+	r.cookieKeys = keys
+}
+
+func (c *Ctx) cookieKeys() []cookieKey {
+	if c.router == nil {
+		return nil
+	}
+	return c.router.cookieKeys
+}
+
+// SetSignedCookie is like SetCookie but signs value with HMAC-SHA256,
+// so that it can be read by the client but can not be tampered with.
+// The expiry given by opts.MaxAge is signed together with value.
+func (c *Ctx) SetSignedCookie(name, value string, opts *CookieOptions) error {
+	keys := c.cookieKeys()
+	if len(keys) == 0 {
+		return errNoCookieKeys
+	}
+	return c.SetCookie(name, signCookie(&keys[0], name, value, cookieExpiry(opts)), opts)
+}
+
+// SignedCookie returns the value of the signed cookie named name. It
+// returns http.ErrNoCookie if there is no such cookie, or ErrInvalidCookie
+// if the cookie is not signed by any of the cookie keys or has expired.
+func (c *Ctx) SignedCookie(name string) (string, error) {
+	s, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return verifyCookie(c.cookieKeys(), name, s, time.Now())
+}
+
+// SetEncryptedCookie is like SetCookie but encrypts value with AES-GCM,
+// so that it can neither be read nor be tampered with by the client.
+// The expiry given by opts.MaxAge is encrypted together with value.
+func (c *Ctx) SetEncryptedCookie(name, value string, opts *CookieOptions) error {
+	keys := c.cookieKeys()
+	if len(keys) == 0 {
+		return errNoCookieKeys
+	}
+	s, err := encryptCookie(&keys[0], name, value, cookieExpiry(opts))
+	if err != nil {
+		return err
+	}
+	return c.SetCookie(name, s, opts)
+}
+
+// EncryptedCookie returns the decrypted value of the encrypted cookie
+// named name. It returns http.ErrNoCookie if there is no such cookie, or
+// ErrInvalidCookie if the cookie can not be decrypted by any of the
+// cookie keys or has expired.
+func (c *Ctx) EncryptedCookie(name string) (string, error) {
+	s, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return decryptCookie(c.cookieKeys(), name, s, time.Now())
+}
+
+// cookieExpiry returns when a cookie set with opts expires, or the zero
+// time if it never expires.
+func cookieExpiry(opts *CookieOptions) time.Time {
+	if opts == nil || opts.MaxAge <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(opts.MaxAge)
+}
+
+// sealPayload prepends the expiry in Unix seconds to value, where 0
+// means the cookie never expires.
+func sealPayload(value string, expires time.Time) []byte {
+	var exp int64
+	if !expires.IsZero() {
+		exp = expires.Unix()
+	}
+	return append(binary.BigEndian.AppendUint64(nil, uint64(exp)), value...)
+}
+
+// openPayload returns the value sealed in payload if it has not expired.
+func openPayload(payload []byte, now time.Time) (string, error) {
+	if len(payload) < 8 {
+		return "", ErrInvalidCookie
+	}
+	exp := int64(binary.BigEndian.Uint64(payload))
+	if exp != 0 && now.Unix() >= exp {
+		return "", ErrInvalidCookie
+	}
+	return string(payload[8:]), nil
+}
+
+// signCookie returns the payload of value and its signature, which
+// covers the cookie name as well so that a cookie can not be renamed.
+func signCookie(key *cookieKey, name, value string, expires time.Time) string {
+	payload := sealPayload(value, expires)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(cookieMAC(key, name, payload))
+}
+
+func cookieMAC(key *cookieKey, name string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key.sign)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func verifyCookie(keys []cookieKey, name, s string, now time.Time) (string, error) {
+	p, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for i := range keys {
+		if hmac.Equal(mac, cookieMAC(&keys[i], name, payload)) {
+			return openPayload(payload, now)
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// encryptCookie returns the nonce followed by the encrypted payload of
+// value, which is authenticated together with the cookie name.
+func encryptCookie(key *cookieKey, name, value string, expires time.Time) (string, error) {
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, sealPayload(value, expires), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func decryptCookie(keys []cookieKey, name, s string, now time.Time) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for i := range keys {
+		n := keys[i].aead.NonceSize()
+		if len(sealed) < n {
+			break
+		}
+		payload, err := keys[i].aead.Open(nil, sealed[:n], sealed[n:], []byte(name))
+		if err == nil {
+			return openPayload(payload, now)
+		}
+	}
+	return "", ErrInvalidCookie
+}
@@ -0,0 +1,169 @@
+package hr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBindCookie(t *testing.T) {
+	type prefs struct {
+		Session string `cookie:"session"`
+		Theme   string `cookie:"theme?" default:"light"`
+		Visits  int    `cookie:"visits?"`
+		Expires *Time  `cookie:"expires?"`
+	}
+
+	r := Default()
+	var got prefs
+	r.GET("/", func(c *Ctx) error {
+		got = prefs{}
+		return c.BindCookie(&got)
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", "session=abc; visits=3; expires=2023-06-05T21:33:45Z")
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("bad status code %d", rw.Code)
+	}
+	if got.Session != "abc" || got.Theme != "light" || got.Visits != 3 || got.Expires == nil {
+		t.Fatalf("bad cookies %+v", got)
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", "visits=many")
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	body := rw.Body.String()
+	if rw.Code != http.StatusBadRequest ||
+		!strings.Contains(body, `"source":"cookie","key":"session"`) ||
+		!strings.Contains(body, `"source":"cookie","key":"visits"`) {
+		t.Fatalf("bad response %d %s", rw.Code, body)
+	}
+}
+
+func TestSetCookie(t *testing.T) {
+	r := Default()
+	r.GET("/", func(c *Ctx) error {
+		if v, err := c.Cookie("old"); err != nil || v != "1" {
+			t.Fatalf("bad cookie %q %v", v, err)
+		}
+		if _, err := c.Cookie("none"); err != http.ErrNoCookie {
+			t.Fatalf("want ErrNoCookie got %v", err)
+		}
+		c.SetCookie("session", "abc", nil)
+		c.SetCookie("theme", "dark", &CookieOptions{
+			Path:       "/app",
+			MaxAge:     time.Hour,
+			SameSite:   http.SameSiteStrictMode,
+			Insecure:   true,
+			Scriptable: true,
+		})
+		c.ClearCookie("old")
+		if err := c.SetCookie("bad name", "", nil); err == nil {
+			t.Fatal("want error of invalid cookie")
+		}
+		c.NoContent()
+		if err := c.SetCookie("late", "", nil); err != ErrCommitted {
+			t.Fatalf("want ErrCommitted got %v", err)
+		}
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", "old=1")
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 3 {
+		t.Fatalf("bad cookies %v", cookies)
+	}
+	session, theme, old := cookies[0], cookies[1], cookies[2]
+	if session.Path != "/" || !session.Secure || !session.HttpOnly || session.SameSite != http.SameSiteLaxMode || session.MaxAge != 0 {
+		t.Fatalf("bad session cookie %v", session)
+	}
+	if theme.Path != "/app" || theme.Secure || theme.HttpOnly || theme.SameSite != http.SameSiteStrictMode || theme.MaxAge != 3600 {
+		t.Fatalf("bad theme cookie %v", theme)
+	}
+	if old.Value != "" || old.MaxAge != -1 {
+		t.Fatalf("bad cleared cookie %v", old)
+	}
+}
+
+func TestSecureCookie(t *testing.T) {
+	k1 := []byte(strings.Repeat("1", 32))
+	k2 := []byte(strings.Repeat("2", 32))
+
+	issue := func(keys ...[]byte) []*http.Cookie {
+		r := Default()
+		r.CookieKeys(keys[0], keys[1:]...)
+		r.GET("/", func(c *Ctx) error {
+			if err := c.SetSignedCookie("signed", "alice", nil); err != nil {
+				return err
+			}
+			return c.SetEncryptedCookie("sealed", "bob", &CookieOptions{MaxAge: time.Hour})
+		})
+		req, _ := http.NewRequest("GET", "/", nil)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		return rw.Result().Cookies()
+	}
+	read := func(cookies []*http.Cookie, keys ...[]byte) (signed, sealed string, err1, err2 error) {
+		r := Default()
+		r.CookieKeys(keys[0], keys[1:]...)
+		r.GET("/", func(c *Ctx) error {
+			signed, err1 = c.SignedCookie("signed")
+			sealed, err2 = c.EncryptedCookie("sealed")
+			return nil
+		})
+		req, _ := http.NewRequest("GET", "/", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return
+	}
+
+	cookies := issue(k1)
+	if len(cookies) != 2 || strings.Contains(cookies[1].Value, "bob") {
+		t.Fatalf("bad cookies %v", cookies)
+	}
+	// k1 is still accepted after rotated to k2.
+	if signed, sealed, err1, err2 := read(cookies, k2, k1); signed != "alice" || sealed != "bob" || err1 != nil || err2 != nil {
+		t.Fatalf("bad cookies %q %q %v %v", signed, sealed, err1, err2)
+	}
+	// k1 is retired.
+	if _, _, err1, err2 := read(cookies, k2); err1 != ErrInvalidCookie || err2 != ErrInvalidCookie {
+		t.Fatalf("want ErrInvalidCookie got %v %v", err1, err2)
+	}
+
+	tampered := []*http.Cookie{
+		{Name: "signed", Value: "x" + cookies[0].Value},
+		{Name: "sealed", Value: cookies[1].Value[:len(cookies[1].Value)-2]},
+	}
+	if _, _, err1, err2 := read(tampered, k1); err1 != ErrInvalidCookie || err2 != ErrInvalidCookie {
+		t.Fatalf("want ErrInvalidCookie got %v %v", err1, err2)
+	}
+	renamed := []*http.Cookie{
+		{Name: "signed", Value: cookies[1].Value},
+		{Name: "sealed", Value: cookies[0].Value},
+	}
+	if _, _, err1, err2 := read(renamed, k1); err1 != ErrInvalidCookie || err2 != ErrInvalidCookie {
+		t.Fatalf("want ErrInvalidCookie got %v %v", err1, err2)
+	}
+
+	keys := []cookieKey{newCookieKey(k1)}
+	past := time.Now().Add(-time.Minute)
+	if _, err := verifyCookie(keys, "a", signCookie(&keys[0], "a", "v", past), time.Now()); err != ErrInvalidCookie {
+		t.Fatalf("want expired got %v", err)
+	}
+	s, _ := encryptCookie(&keys[0], "a", "v", past)
+	if _, err := decryptCookie(keys, "a", s, time.Now()); err != ErrInvalidCookie {
+		t.Fatalf("want expired got %v", err)
+	}
+}
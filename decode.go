@@ -0,0 +1,147 @@
+package hr
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DecodeOptions control how Ctx.Bind decodes request bodies. Zero values
+// mean the lax defaults of the standard decoders.
+type DecodeOptions struct {
+	// MaxBodySize limits the size of request bodies, which results in a
+	// 413 (request entity too large) error if exceeded. It applies to
+	// bodies of any content type.
+	MaxBodySize int64
+	// DisallowUnknownFields rejects JSON objects with keys that do not
+	// match any field of the struct decoded to.
+	DisallowUnknownFields bool
+	// DisallowTrailingData rejects bodies with anything but white spaces
+	// following the value decoded. It applies to JSON, XML, Gob and CBOR.
+	DisallowTrailingData bool
+	// UseNumber decodes JSON numbers to interface{} as json.Number rather
+	// than float64.
+	UseNumber bool
+	// MaxDepth limits how deep objects and arrays of JSON, elements of
+	// XML and data items of CBOR can be nested. Gob has no such limit, so
+	// Gob bodies are rejected with 415 (unsupported media type) if it is
+	// set.
+	MaxDepth int
+}
+
+// Decoding returns a plugin that decodes request bodies bound to by the
+// routes it is applied to with opts. Options given to a route take
+// precedence over those given to the router or a group.
+//
+// Example:
+//
+//	r := hr.Default(hr.Decoding(hr.DecodeOptions{
+//	    MaxBodySize:           1 << 20,
+//	    DisallowUnknownFields: true,
+//	    DisallowTrailingData:  true,
+//	    MaxDepth:              32,
+//	}))
+func Decoding(opts DecodeOptions) Plugin {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(c *Ctx) error {
+			c.decoding = &opts
+			return next.ServeHTTP(c)
+		})
+	}
+}
+
+// optionsDecoder is implemented by codecs that honor DecodeOptions.
+// Other codecs are only limited by MaxBodySize.
+type optionsDecoder interface {
+	decode(r io.Reader, v interface{}, opts *DecodeOptions) error
+}
+
+var (
+	errTooDeep      = errors.New("exceeded max depth")
+	errTrailingData = errors.New("trailing data after the value")
+)
+
+// jsonDepthReader fails reading a JSON text nested deeper than max as it
+// passes through. The error is kept in err, since JSON decoders may
+// report it as an unexpected EOF.
+type jsonDepthReader struct {
+	r       io.Reader
+	max     int
+	depth   int
+	str     bool // whether in a string.
+	escaped bool // whether the last byte in a string is a backslash.
+	err     error
+}
+
+func (d *jsonDepthReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	for i, c := range p[:n] {
+		switch {
+		case d.escaped:
+			d.escaped = false
+		case d.str:
+			if c == '\\' {
+				d.escaped = true
+			} else if c == '"' {
+				d.str = false
+			}
+		case c == '"':
+			d.str = true
+		case c == '{' || c == '[':
+			if d.depth++; d.depth > d.max {
+				d.err = fmt.Errorf("json: %w of %d", errTooDeep, d.max)
+				return i, d.err
+			}
+		case c == '}' || c == ']':
+			d.depth--
+		}
+	}
+	return n, err
+}
+
+// xmlTokenReader reads raw tokens from an XML decoder, failing if
+// elements are nested deeper than max. Tokens are checked and translated
+// by the decoder reading from it.
+type xmlTokenReader struct {
+	dec   *xml.Decoder
+	max   int
+	depth int
+}
+
+func (t *xmlTokenReader) Token() (xml.Token, error) {
+	tok, err := t.dec.RawToken()
+	switch tok.(type) {
+	case xml.StartElement:
+		if t.depth++; t.max > 0 && t.depth > t.max {
+			return nil, fmt.Errorf("xml: %w of %d", errTooDeep, t.max)
+		}
+	case xml.EndElement:
+		t.depth--
+	}
+	return tok, err
+}
+
+// trailing returns errTrailingData if anything but white spaces,
+// comments and processing instructions follows the root element.
+func (t *xmlTokenReader) trailing() error {
+	for {
+		tok, err := t.dec.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.Comment, xml.ProcInst:
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) > 0 {
+				return fmt.Errorf("xml: %w", errTrailingData)
+			}
+		default:
+			return fmt.Errorf("xml: %w", errTrailingData)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package hr
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeOptions(t *testing.T) {
+	type doc struct {
+		Name  string      `json:"name" xml:"name" cbor:"name"`
+		Extra interface{} `json:"extra" xml:"extra" cbor:"extra"`
+	}
+
+	var got doc
+	r := Default(Decoding(DecodeOptions{MaxBodySize: 64}))
+	handler := func(c *Ctx) error {
+		got = doc{}
+		if err := c.Bind(&got); err != nil {
+			return err
+		}
+		return c.NoContent()
+	}
+	r.POST("/lax", handler)
+	r.POST("/trailing", handler, Decoding(DecodeOptions{DisallowTrailingData: true}))
+	r.POST("/strict", handler, Decoding(DecodeOptions{
+		MaxBodySize:           128,
+		DisallowUnknownFields: true,
+		DisallowTrailingData:  true,
+		UseNumber:             true,
+		MaxDepth:              3,
+	}))
+
+	var gobBody bytes.Buffer
+	gob.NewEncoder(&gobBody).Encode(doc{Name: "gob"})
+	gobTrailing := gobBody.String() + "x"
+
+	cases := []struct {
+		path, ctype, body string
+		code              int
+		detail            string
+	}{
+		{"/lax", "application/json", `{"name":"a","age":1} {}`, http.StatusNoContent, ""},
+		{"/lax", "application/json", `{"name":"` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge, "at most 64 bytes"},
+		{"/lax", "application/x-www-form-urlencoded", "name=" + strings.Repeat("a", 64), http.StatusRequestEntityTooLarge, "at most 64 bytes"},
+		{"/strict", "application/json", `{"name":"a","extra":[1,{"b":"[[["}]}` + "\n", http.StatusNoContent, ""},
+		{"/strict", "application/json", `{"name":"` + strings.Repeat("a", 128) + `"}`, http.StatusRequestEntityTooLarge, "at most 128 bytes"},
+		{"/strict", "application/json", `{"name":"a","age":1}`, http.StatusBadRequest, "unknown field"},
+		{"/strict", "application/json", `{"name":"a"} {}`, http.StatusBadRequest, "trailing data"},
+		{"/strict", "application/json", `{"name":"a","extra":[[[1]]]}`, http.StatusBadRequest, "max depth"},
+		{"/strict", "application/xml", `<doc><name>a</name></doc> <!-- end -->`, http.StatusNoContent, ""},
+		{"/strict", "application/xml", `<doc><name>a</name></doc><doc/>`, http.StatusBadRequest, "trailing data"},
+		{"/strict", "application/xml", `<doc><extra><a><b>1</b></a></extra></doc>`, http.StatusBadRequest, "max depth"},
+		{"/trailing", "application/gob", gobBody.String(), http.StatusNoContent, ""},
+		{"/lax", "application/gob", gobTrailing, http.StatusNoContent, ""},
+		{"/trailing", "application/gob", gobTrailing, http.StatusBadRequest, "trailing data"},
+		{"/strict", "application/gob", gobBody.String(), http.StatusUnsupportedMediaType, "max depth"},
+		{"/strict", "application/cbor", "\xa1\x64name\x61a\x00", http.StatusBadRequest, "trailing data"},
+		{"/strict", "application/cbor", "\xa1\x65extra\x81\x81\x81\x01", http.StatusBadRequest, "max depth"},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest("POST", v.path, strings.NewReader(v.body))
+		req.Header.Set("Content-Type", v.ctype)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code || !strings.Contains(rw.Body.String(), v.detail) {
+			t.Fatalf("%s %s %q: want %d %s got %d %s", v.path, v.ctype, v.body, v.code, v.detail, rw.Code, rw.Body)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/strict", strings.NewReader(`{"name":"a","extra":1.0}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if n, ok := got.Extra.(json.Number); !ok || n != "1.0" {
+		t.Fatalf("want json.Number got %#v", got.Extra)
+	}
+}
@@ -1,18 +1,62 @@
 package hr
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
+// ErrCommitted is returned by response helpers of Ctx when the response
+// header has already been sent.
+var ErrCommitted = errors.New("response already committed")
+
 type Error struct {
-	Code   int
-	Detail string
+	Code   int    `json:"code"`
+	Detail string `json:"detail"`
+
+	// fields are the fields failed, which are held by a pointer to keep
+	// Error comparable.
+	fields *BindError
 }
 
 func (e Error) Error() string {
-	return fmt.Sprintf(`{"code":%d,"detail":"%s"}`, e.Code, e.Detail)
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+// Fields returns the fields of the request failed to bind or to validate,
+// which are sent as "errors", if any.
+func (e Error) Fields() []FieldError {
+	if e.fields == nil {
+		return nil
+	}
+	return e.fields.Fields
+}
+
+// errorJSON is what Error is encoded to in JSON.
+type errorJSON struct {
+	Code   int          `json:"code"`
+	Detail string       `json:"detail"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+func (e Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorJSON{Code: e.Code, Detail: e.Detail, Errors: e.Fields()})
+}
+
+func (e *Error) UnmarshalJSON(b []byte) error {
+	var v errorJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = Error{Code: v.Code, Detail: v.Detail}
+	if len(v.Errors) > 0 {
+		e.fields = &BindError{Fields: v.Errors}
+	}
+	return nil
 }
 
 func (e Error) WriteTo(w io.Writer) (int64, error) {
@@ -35,12 +79,109 @@ func ParseError(err error) Error {
 	if herr, ok := err.(Error); ok {
 		return herr
 	}
+	var berr *BindError
+	if errors.As(err, &berr) {
+		return berr.HTTPError()
+	}
 	return Error{
 		Code:   http.StatusInternalServerError,
 		Detail: err.Error(),
 	}
 }
 
+// FieldError describes a field of a request that failed to bind or to
+// validate.
+type FieldError struct {
+	// Source is where the field comes from, which is one of path, query,
+	// header, cookie, form and body.
+	Source string `json:"source"`
+	// Key is the key of the field in the source. Keys of nested fields
+	// are dotted, like items.0.sku.
+	Key string `json:"key"`
+	// Rule is the validation rule the field failed, if any.
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+
+	err  error
+	path string // the path of the field missing, if any.
+}
+
+func (e FieldError) Error() string {
+	if len(e.Rule) > 0 {
+		return fmt.Sprintf("%s: %s (%s)", e.Message, e.Key, e.Rule)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Key)
+}
+
+// Unwrap returns the error that made the field fail to bind, such as
+// a parsing error.
+func (e FieldError) Unwrap() error {
+	return e.err
+}
+
+// BindError collects every field of a request that failed to bind or
+// to validate. It results in a 400 (bad request) response listing them.
+type BindError struct {
+	Fields []FieldError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// HTTPError returns the hr.Error e is sent as.
+func (e *BindError) HTTPError() Error {
+	return Error{
+		Code:   http.StatusBadRequest,
+		Detail: e.Error(),
+		fields: e,
+	}
+}
+
+// collect adds the fields of err to e if err is a *BindError, skipping
+// those of the same sources and keys as ones which have been added.
+// Other errors are returned as is.
+func (e *BindError) collect(err error) error {
+	berr, ok := err.(*BindError)
+	if !ok {
+		return err
+	}
+next:
+	for _, f := range berr.Fields {
+		for _, g := range e.Fields {
+			if f.Source == g.Source && f.Key == g.Key {
+				continue next
+			}
+		}
+		e.Fields = append(e.Fields, f)
+	}
+	return nil
+}
+
+// resolve drops the fields missing from a source but bound from another,
+// which are in bound or given if given is not nil.
+func (e *BindError) resolve(bound map[string]bool, given func(path string) bool) {
+	fields := e.Fields[:0]
+	for _, f := range e.Fields {
+		if len(f.path) == 0 || !bound[f.path] && (given == nil || !given(f.path)) {
+			fields = append(fields, f)
+		}
+	}
+	e.Fields = fields
+}
+
+// err returns e if there is any field failed, otherwise nil.
+func (e *BindError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
 func BadRequest(format string, v ...interface{}) Error {
 	return Error{Code: http.StatusBadRequest, Detail: fmt.Sprintf(format, v...)}
 }
@@ -57,6 +198,26 @@ func NotFound(format string, v ...interface{}) Error {
 	return Error{Code: http.StatusNotFound, Detail: fmt.Sprintf(format, v...)}
 }
 
+func NotAcceptable(format string, v ...interface{}) Error {
+	return Error{Code: http.StatusNotAcceptable, Detail: fmt.Sprintf(format, v...)}
+}
+
+func Conflict(format string, v ...interface{}) Error {
+	return Error{Code: http.StatusConflict, Detail: fmt.Sprintf(format, v...)}
+}
+
+func RequestEntityTooLarge(format string, v ...interface{}) Error {
+	return Error{Code: http.StatusRequestEntityTooLarge, Detail: fmt.Sprintf(format, v...)}
+}
+
+func UnsupportedMediaType(format string, v ...interface{}) Error {
+	return Error{Code: http.StatusUnsupportedMediaType, Detail: fmt.Sprintf(format, v...)}
+}
+
+func UnprocessableEntity(format string, v ...interface{}) Error {
+	return Error{Code: http.StatusUnprocessableEntity, Detail: fmt.Sprintf(format, v...)}
+}
+
 func InternalServerError(format string, v ...interface{}) Error {
 	return Error{Code: http.StatusInternalServerError, Detail: fmt.Sprintf(format, v...)}
 }
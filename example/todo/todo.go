@@ -53,7 +53,7 @@ func main() {
 		todo.Id = int(nextid.Add(1))
 		todo.Time = hr.Time(time.Now())
 		store.Store(todo.Id, &todo)
-		return nil
+		return c.Created("/todo/"+strconv.Itoa(todo.Id), &todo)
 	})
 
 	r.GET("/todo", func(c *hr.Ctx) error {
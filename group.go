@@ -13,6 +13,15 @@ type Group struct {
 	trees   [12]*node
 	chunks  *sync.Pool
 	plugins []Plugin
+	routes  []routeEntry // routes registered, kept by the root group only.
+}
+
+// routeEntry is a route registered with the handler given, before plugins
+// are applied.
+type routeEntry struct {
+	method  string
+	path    string
+	handler Handler
 }
 
 func (g *Group) Prefix(prefix string, plugins ...Plugin) *Group {
@@ -26,6 +35,7 @@ func (g *Group) Prefix(prefix string, plugins ...Plugin) *Group {
 func (g *Group) Handle(method, route string, handler Handler, plugins ...Plugin) {
 	if g.prev == nil {
 		g.handle(method, route, handler, plugins...)
+		g.routes = append(g.routes, routeEntry{method, path.Join("/", g.prefix, route), handler})
 		return
 	}
 	ps := plugins
@@ -0,0 +1,271 @@
+package hr
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Binder is implemented by types binding requests to themselves, like
+// those given BindHR methods by cmd/hrgen, which Ctx.Bind and
+// Ctx.BindAll prefer to binding by reflection.
+type Binder interface {
+	BindHR(c *Ctx) error
+}
+
+// BindFunc binds the request to v and validates v like Bind, but the
+// fields tagged with `path`, `query`, `header`, `cookie` and `form` are
+// bound by fn rather than by reflection. fn is called once for every
+// source of values, in the order of precedence, to bind the fields
+// tagged for the source with BindValue, BindSlice and BindPointer, given
+// the paths of the fields in v, like Paging.Page, by which fields bound
+// are tracked. Bodies decoded by codecs and uploaded files are bound as
+// they are by Bind. The header and cookies are bound as well if
+// BindAll is binding v.
+//
+// It is what BindHR methods generated by cmd/hrgen call, which should
+// be preferred to calling it by hand.
+func (c *Ctx) BindFunc(v interface{}, fn func(s *Source)) error {
+	c.binding = fn
+	defer func() { c.binding = nil }()
+
+	var errs BindError
+	if err := c.bind(v, &errs, c.all); err != nil {
+		return bindError(err)
+	}
+	return c.validate(v, &errs)
+}
+
+// Source is a source of values being bound by BindFunc.
+type Source struct {
+	b   *binder
+	err error // the first error in the tags of the fields, if any.
+}
+
+// Tag returns the tag of the fields bound from s, like "query".
+func (s *Source) Tag() string {
+	return s.b.tag
+}
+
+// leaf returns the values of the leaf field at path, like binder.leaf
+// does, unless the field has been bound or its tags are bad.
+func (s *Source) leaf(key string, tags *Tags, list bool, path string) ([]string, bool) {
+	if s.err != nil || s.b.skip(path) {
+		return nil, false
+	}
+	if len(tags.Collection) > 0 {
+		if err := checkCollection(tags.Collection); err != nil {
+			s.fail(path, err)
+			return nil, false
+		}
+	}
+	f := field{
+		key:    key,
+		opt:    tags.Optional,
+		def:    tags.Default,
+		hasDef: tags.HasDefault,
+		layout: tags.Layout,
+		coll:   tags.Collection,
+	}
+	if len(tags.Mod) > 0 {
+		v, ok := tagModifiers.Load(tags.Mod)
+		if !ok {
+			var m compiledModifiers
+			m.mods, m.err = compileModifiers(strings.Split(tags.Mod, ","))
+			v, _ = tagModifiers.LoadOrStore(tags.Mod, m)
+		}
+		m := v.(compiledModifiers)
+		if m.err != nil {
+			s.fail(path, m.err)
+			return nil, false
+		}
+		f.mods = m.mods
+	}
+	return s.b.leaf(key, &f, list, path)
+}
+
+// fail records the error in the tags of the field at path, which stops
+// s binding.
+func (s *Source) fail(path string, err error) {
+	if s.err == nil {
+		s.err = fmt.Errorf("hr: field %s: %w", path, err)
+	}
+}
+
+// layout reports whether the layout in tags, if any, applies to the field
+// at path of type t, failing s if not.
+func (s *Source) layout(path string, tags *Tags, t reflect.Type) bool {
+	if len(tags.Layout) == 0 || layoutType(t) {
+		return true
+	}
+	s.fail(path, layoutError(t))
+	return false
+}
+
+type compiledModifiers struct {
+	mods []func(string) string
+	err  error
+}
+
+var tagModifiers sync.Map // map[string]compiledModifiers
+
+// bindFunc binds values by fn.
+func (b *binder) bindFunc(values map[string][]string, fn func(s *Source)) error {
+	b.setValues(values)
+	s := &Source{b: b}
+	fn(s)
+	if s.err != nil {
+		return InternalServerError("%v", s.err)
+	}
+	return b.errs.err()
+}
+
+// Tags are the tags of a field bound by BindValue, BindSlice and
+// BindPointer besides its key. See Ctx.Bind for what they mean.
+type Tags struct {
+	Optional   bool   // whether the key ends with '?'.
+	Default    string // the value of the `default` tag.
+	HasDefault bool   // whether there is a `default` tag.
+	Mod        string // the value of the `mod` tag.
+	Layout     string // the value of the `layout` tag.
+	Collection string // the value of the `collection` tag.
+}
+
+// BindValue binds the values keyed by key in s to the field p at path,
+// which is set from the first value.
+func BindValue[T any](s *Source, p *T, path, key string, tags Tags) {
+	if !s.layout(path, &tags, reflect.TypeOf(p).Elem()) {
+		return
+	}
+	if vals, ok := s.leaf(key, &tags, false, path); ok {
+		s.b.result(key, path, parseValue(p, vals[0], tags.Layout))
+	}
+}
+
+// BindSlice binds the values keyed by key in s to the slice field p at
+// path in its collection format.
+func BindSlice[T any](s *Source, p *[]T, path, key string, tags Tags) {
+	if !s.layout(path, &tags, reflect.TypeOf(p).Elem()) {
+		return
+	}
+	vals, ok := s.leaf(key, &tags, true, path)
+	if !ok {
+		return
+	}
+	slice := make([]T, len(vals))
+	for i, v := range vals {
+		if err := parseValue(&slice[i], v, tags.Layout); err != nil {
+			s.b.result(key, path, err)
+			return
+		}
+	}
+	*p = slice
+	s.b.result(key, path, nil)
+}
+
+// BindPointer binds the values keyed by key in s to the pointer field p
+// at path, which is allocated if nil and set from the first value.
+func BindPointer[T any](s *Source, p **T, path, key string, tags Tags) {
+	if !s.layout(path, &tags, reflect.TypeOf(p).Elem()) {
+		return
+	}
+	if vals, ok := s.leaf(key, &tags, false, path); ok {
+		if *p == nil {
+			*p = new(T)
+		}
+		s.b.result(key, path, parseValue(*p, vals[0], tags.Layout))
+	}
+}
+
+type signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+type unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+func setSigned[T signed](p *T, s string, bits int) error {
+	n, err := parseInt(s, bits)
+	if err == nil {
+		*p = T(n)
+	}
+	return err
+}
+
+func setUnsigned[T unsigned](p *T, s string, bits int) error {
+	n, err := parseUint(s, bits)
+	if err == nil {
+		*p = T(n)
+	}
+	return err
+}
+
+// parseValue sets p from s like the setters of reflective binding do.
+// Values of the types not listed are still set by reflection.
+func parseValue[T any](p *T, s, layout string) error {
+	if len(layout) > 0 {
+		if t, ok := any(p).(*time.Time); ok {
+			v, err := time.Parse(layout, s)
+			if err == nil {
+				*t = v
+			}
+			return err
+		}
+		return setTimes(reflect.ValueOf(p).Elem(), []string{s}, layout)
+	}
+
+	switch p := any(p).(type) {
+	case Type:
+		return p.Parse(s)
+	case encoding.TextUnmarshaler:
+		return p.UnmarshalText([]byte(s))
+	case *string:
+		*p = s
+		return nil
+	case *int:
+		return setSigned(p, s, strconv.IntSize)
+	case *int8:
+		return setSigned(p, s, 8)
+	case *int16:
+		return setSigned(p, s, 16)
+	case *int32:
+		return setSigned(p, s, 32)
+	case *int64:
+		return setSigned(p, s, 64)
+	case *uint:
+		return setUnsigned(p, s, strconv.IntSize)
+	case *uint8:
+		return setUnsigned(p, s, 8)
+	case *uint16:
+		return setUnsigned(p, s, 16)
+	case *uint32:
+		return setUnsigned(p, s, 32)
+	case *uint64:
+		return setUnsigned(p, s, 64)
+	case *float32:
+		f, err := parseFloat(s, 32)
+		if err == nil {
+			*p = float32(f)
+		}
+		return err
+	case *float64:
+		f, err := parseFloat(s, 64)
+		if err == nil {
+			*p = f
+		}
+		return err
+	case *bool:
+		b, err := parseBool(s)
+		if err == nil {
+			*p = b
+		}
+		return err
+	}
+	v := reflect.ValueOf(p).Elem()
+	return newSetter(v.Type())(s, v)
+}
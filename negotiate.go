@@ -0,0 +1,120 @@
+package hr
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Negotiate sends v with the given status code, encoded in the media
+// type that is most preferred by the Accept request header among those
+// of the codecs registered on the router. Media types that are equally
+// preferred by the client are chosen in the order of registration, which
+// starts with JSON, XML, Gob and CBOR. If none of them is acceptable, an
+// hr.Error resulting in a 406 (not acceptable) response is returned.
+func (c *Ctx) Negotiate(code int, v interface{}) error {
+	// the response varies on the Accept header no matter what is chosen.
+	if !c.rw.committed && !varies(c.rw.Header(), "Accept") {
+		c.rw.Header().Add("Vary", "Accept")
+	}
+
+	codecs := c.codecs()
+	offers := make([]string, len(codecs))
+	for i, codec := range codecs {
+		offers[i] = codec.MediaType()
+	}
+	i := negotiate(c.req.Header.Values("Accept"), offers)
+	if i < 0 {
+		return NotAcceptable("acceptable media types: %s", strings.Join(offers, ", "))
+	}
+	if err := c.commit(code, offers[i]); err != nil {
+		return err
+	}
+	return codecs[i].Encode(&c.rw, v)
+}
+
+// varies reports whether the Vary header in h lists the header name.
+func varies(h http.Header, name string) bool {
+	for _, v := range h.Values("Vary") {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == "*" || strings.EqualFold(s, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// accept is a media range in the Accept header.
+type accept struct {
+	typ, sub string
+	q        float64
+}
+
+// parseAccept parses the values of the Accept header. Malformed media
+// ranges are ignored.
+func parseAccept(values []string) []accept {
+	var ranges []accept
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			params := strings.Split(s, ";")
+			typ, sub, ok := strings.Cut(strings.TrimSpace(params[0]), "/")
+			if !ok || len(typ) == 0 || len(sub) == 0 {
+				continue
+			}
+			a := accept{typ: strings.ToLower(typ), sub: strings.ToLower(sub), q: 1}
+			for _, p := range params[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				if strings.TrimSpace(strings.ToLower(k)) != "q" {
+					continue
+				}
+				if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q >= 0 && q <= 1 {
+					a.q = q
+				}
+			}
+			ranges = append(ranges, a)
+		}
+	}
+	return ranges
+}
+
+// negotiate returns the index of the offered media type the Accept
+// header values prefer the most, or -1 if none of them is acceptable.
+// Every offer is weighed by the quality of the most specific media range
+// matching it, and ties are broken by the order of offers. Any offer is
+// acceptable if there is no Accept header at all.
+func negotiate(values []string, offers []string) int {
+	ranges := parseAccept(values)
+	if len(ranges) == 0 {
+		// an empty Accept header is taken as if there is none.
+		if len(strings.TrimSpace(strings.Join(values, ""))) == 0 && len(offers) > 0 {
+			return 0
+		}
+		return -1
+	}
+
+	best, bestq := -1, 0.0
+	for i, offer := range offers {
+		typ, sub, _ := strings.Cut(offer, "/")
+		q, spec := 0.0, -1
+		for _, a := range ranges {
+			s := 0
+			switch {
+			case a.typ == typ && a.sub == sub:
+				s = 2
+			case a.typ == typ && a.sub == "*":
+				s = 1
+			case a.typ == "*" && a.sub == "*":
+			default:
+				continue
+			}
+			if s > spec {
+				q, spec = a.q, s
+			}
+		}
+		if q > bestq {
+			best, bestq = i, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,82 @@
+package hr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "application/gob"}
+	cases := []struct {
+		accept []string
+		want   int
+	}{
+		{nil, 0},
+		{[]string{""}, 0},
+		{[]string{"*/*"}, 0},
+		{[]string{"application/xml"}, 1},
+		{[]string{"text/html, application/*;q=0.5, application/gob"}, 2},
+		{[]string{"application/json;q=0.2, application/xml;q=0.8"}, 1},
+		{[]string{"application/*;q=0.3", "application/xml;q=0"}, 0},
+		{[]string{"*/*;q=0.1, application/json;q=0"}, 1},
+		{[]string{"text/html"}, -1},
+		{[]string{"garbage"}, -1},
+	}
+	for _, v := range cases {
+		if got := negotiate(v.accept, offers); got != v.want {
+			t.Fatalf("%q: want %d got %d", v.accept, v.want, got)
+		}
+	}
+}
+
+func TestCtxNegotiate(t *testing.T) {
+	r := Default()
+	r.GET("/", func(c *Ctx) error {
+		return c.Negotiate(http.StatusOK, struct{ Answer int }{42})
+	})
+
+	cases := []struct {
+		accept string
+		code   int
+		ctype  string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"application/xml, application/json;q=0.9", http.StatusOK, "application/xml"},
+		{"text/html", http.StatusNotAcceptable, "application/json; charset=utf-8"},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if len(v.accept) > 0 {
+			req.Header.Set("Accept", v.accept)
+		}
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code {
+			t.Fatalf("[%s] bad status code, want %d got %d", v.accept, v.code, rw.Code)
+		}
+		if got := rw.Header().Get("Content-Type"); got != v.ctype {
+			t.Fatalf("[%s] bad content type %q", v.accept, got)
+		}
+		if got := rw.Header().Get("Vary"); got != "Accept" {
+			t.Fatalf("[%s] bad vary header %q", v.accept, got)
+		}
+	}
+
+	r.GET("/twice", func(c *Ctx) error {
+		h := c.ResponseWriter().Header()
+		h.Set("Vary", "Origin, accept")
+		c.Negotiate(http.StatusOK, 1)
+		h.Del("Vary")
+		if err := c.Negotiate(http.StatusOK, 2); err != ErrCommitted || len(h.Values("Vary")) > 0 {
+			t.Errorf("want no vary header after commit got %q", h.Values("Vary"))
+		}
+		return nil
+	})
+	req, _ := http.NewRequest("GET", "/twice", nil)
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if got := rw.Result().Header.Values("Vary"); len(got) != 1 || got[0] != "Origin, accept" {
+		t.Fatalf("bad vary header %q", got)
+	}
+}
@@ -0,0 +1,527 @@
+package hr
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPI is an OpenAPI 3.1 document, see Router.OpenAPI.
+type OpenAPI struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info is the metadata of an API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem is the operations of a path by lower-cased methods.
+type PathItem map[string]*Operation
+
+// Operation describes a route of a method.
+type Operation struct {
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter describes a parameter of an operation, which is in one of
+// path, query, header and cookie.
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required,omitempty"`
+	Style    string `json:"style,omitempty"`
+	Explode  *bool  `json:"explode,omitempty"`
+	Schema   Schema `json:"schema"`
+}
+
+// RequestBody describes the request body of an operation.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType describes a body in a media type.
+type MediaType struct {
+	Schema Schema `json:"schema,omitempty"`
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components holds the schemas referred to by the document.
+type Components struct {
+	Schemas map[string]Schema `json:"schemas,omitempty"`
+}
+
+// signaturer is implemented by typed handlers, see TypedStatus.
+type signaturer interface {
+	signature() (in, out reflect.Type, code int)
+}
+
+// paramStyles are the styles of query parameters bound in collection
+// formats, see collectionFormats. Parameters in the brackets format are
+// named with [] appended, and those in the indexed format are described
+// as objects keyed by indexes, which deepObject is meant for.
+var paramStyles = map[string]struct {
+	style   string
+	explode bool
+}{
+	"multi":    {"form", true},
+	"brackets": {"form", true},
+	"csv":      {"form", false},
+	"ssv":      {"spaceDelimited", false},
+	"pipes":    {"pipeDelimited", false},
+	"indexed":  {"deepObject", true},
+}
+
+// OpenAPI returns the OpenAPI 3.1 document of the routes registered on r,
+// with path parameters from the variables of the routes. Handlers made by
+// Typed and TypedStatus describe more of their routes: parameters from
+// the fields of their inputs tagged with path, query, header and cookie,
+// request bodies from the rest of the fields and responses from their
+// outputs, whose schemas are given in JSON media types only. Errors are
+// described as Error.
+func (r *Router) OpenAPI(info Info) *OpenAPI {
+	g := newSchemaGen("#/components/schemas/")
+	doc := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+	errResp := Response{
+		Description: "Error",
+		Content: map[string]MediaType{"application/json": {
+			// Error is described by what it is encoded to.
+			Schema: Schema{"$ref": g.ref + g.define(reflect.TypeOf(errorJSON{}), "Error")},
+		}},
+	}
+	for _, route := range r.routes {
+		p, vars := openAPIPath(route.path)
+		item := doc.Paths[p]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[p] = item
+		}
+		op := r.operation(g, route, vars)
+		op.Responses["default"] = errResp
+		item[strings.ToLower(route.method)] = op
+	}
+	doc.Components.Schemas = g.defs
+	return doc
+}
+
+// openAPIPath returns the path template of route, like /users/{id} of
+// /users/:id, and the names of its variables.
+func openAPIPath(route string) (string, []string) {
+	var vars []string
+	chunks := strings.Split(route, "/")
+	for i, chunk := range chunks {
+		if len(chunk) > 0 && chunk[0] == vardec {
+			vars = append(vars, chunk[1:])
+			chunks[i] = "{" + chunk[1:] + "}"
+		}
+	}
+	return strings.Join(chunks, "/"), vars
+}
+
+func (r *Router) operation(g *schemaGen, route routeEntry, vars []string) *Operation {
+	op := &Operation{Responses: make(map[string]Response)}
+	h, ok := route.handler.(signaturer)
+	if !ok {
+		for _, name := range vars {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   Schema{"type": "string"},
+			})
+		}
+		return op
+	}
+	in, out, code := h.signature()
+
+	pathParams := make(map[string]Parameter)
+	for _, p := range r.params(g, nil, in, "path", "", make(map[reflect.Type]bool)) {
+		pathParams[p.Name] = p
+	}
+	for _, name := range vars {
+		p, ok := pathParams[name]
+		if !ok {
+			p = Parameter{Name: name, In: "path", Schema: Schema{"type": "string"}}
+		}
+		p.Required = true
+		op.Parameters = append(op.Parameters, p)
+	}
+	for _, tag := range []string{"query", "header", "cookie"} {
+		op.Parameters = r.params(g, op.Parameters, in, tag, "", make(map[reflect.Type]bool))
+	}
+
+	switch route.method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+	default:
+		op.RequestBody = r.requestBody(g, in)
+	}
+
+	resp := Response{Description: http.StatusText(code)}
+	if code != http.StatusNoContent {
+		resp.Content = r.content(g.schema(out))
+	}
+	op.Responses[strconv.Itoa(code)] = resp
+	return op
+}
+
+// content returns the content of bodies in the media types of the codecs
+// of r, described by the schema s if they are JSON.
+func (r *Router) content(s Schema) map[string]MediaType {
+	content := make(map[string]MediaType, len(r.codecs))
+	for _, c := range r.codecs {
+		mt := c.MediaType()
+		if jsonMediaType(mt) {
+			content[mt] = MediaType{Schema: s}
+		} else {
+			content[mt] = MediaType{}
+		}
+	}
+	return content
+}
+
+// jsonMediaType reports whether the media type mt is JSON, including
+// those with the +json suffix, whose bodies JSON schemas describe.
+func jsonMediaType(mt string) bool {
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// params appends the parameters bound from tag to the fields of the
+// struct type t to ps. Keys of nested structs are prefixed by prefix.
+// Structs in seen, which are those being described, are skipped so that
+// recursive types end.
+func (r *Router) params(g *schemaGen, ps []Parameter, t reflect.Type, tag, prefix string, seen map[reflect.Type]bool) []Parameter {
+	if seen[t] {
+		return ps
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get(tag)
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer && !isLeaf(ft) {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && len(key) == 0 && !isType(f.Type) {
+			if ft.Kind() == reflect.Struct {
+				ps = r.params(g, ps, ft, tag, prefix, seen)
+			}
+			continue
+		}
+		if !f.IsExported() || len(key) == 0 {
+			continue
+		}
+		opt := key[len(key)-1] == '?'
+		if opt {
+			key = key[:len(key)-1]
+		}
+		if !isLeaf(ft) {
+			if ft.Kind() == reflect.Struct {
+				ps = r.params(g, ps, ft, tag, prefix+key+".", seen)
+			}
+			continue
+		}
+
+		p := Parameter{Name: prefix + key, In: tag, Required: !opt, Schema: g.paramSchema(ft)}
+		if def, ok := f.Tag.Lookup("default"); ok {
+			p.Required = false
+			p.Schema["default"] = p.Schema.valueOf(def)
+		}
+		if rules := f.Tag.Get("validate"); len(rules) > 0 && p.Schema.validation(splitRules(rules)) {
+			p.Required = true
+		}
+		if tag == "query" && p.Schema["type"] == "array" {
+			coll := f.Tag.Get("collection")
+			if len(coll) == 0 {
+				coll = r.collection
+			}
+			if len(coll) == 0 {
+				coll = "multi"
+			}
+			if style, ok := paramStyles[coll]; ok {
+				explode := style.explode
+				p.Style, p.Explode = style.style, &explode
+			}
+			switch coll {
+			case "brackets":
+				p.Name += "[]"
+			case "indexed":
+				p.Schema = indexedSchema(p.Schema)
+			}
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// indexedSchema returns the schema of the array schema s bound in the
+// indexed format, which is an object keyed by the indexes of the items.
+func indexedSchema(s Schema) Schema {
+	o := Schema{
+		"type":                 "object",
+		"propertyNames":        Schema{"pattern": "^(0|[1-9][0-9]*)$"},
+		"additionalProperties": s["items"],
+	}
+	if n, ok := s["minItems"]; ok {
+		o["minProperties"] = n
+	}
+	if n, ok := s["maxItems"]; ok {
+		o["maxProperties"] = n
+	}
+	return o
+}
+
+// requestBody returns the request body of an operation whose input is of
+// the struct type t, or nil if it has none. Fields tagged with `form` or
+// `file` are described by forms, and fields not tagged with any source
+// but `json` are described by bodies of the codecs of r.
+func (r *Router) requestBody(g *schemaGen, t reflect.Type) *RequestBody {
+	content := make(map[string]MediaType)
+	body := g.object(t, func(f reflect.StructField) bool {
+		if _, ok := f.Tag.Lookup("json"); ok {
+			return false
+		}
+		for _, tag := range []string{"path", "query", "header", "cookie", "form", "file"} {
+			if _, ok := f.Tag.Lookup(tag); ok {
+				return true
+			}
+		}
+		return false
+	})
+	if len(body["properties"].(map[string]interface{})) > 0 {
+		content = r.content(body)
+	}
+
+	form := Schema{"type": "object", "properties": make(map[string]interface{})}
+	multipart := formProperties(g, form, t, "", make(map[reflect.Type]bool))
+	if props := form["properties"].(map[string]interface{}); len(props) > 0 {
+		if multipart {
+			content["multipart/form-data"] = MediaType{Schema: form}
+		} else {
+			content["application/x-www-form-urlencoded"] = MediaType{Schema: form}
+		}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	return &RequestBody{Content: content}
+}
+
+// formProperties adds the fields of the struct type t tagged with `form`
+// or `file` to the properties of the schema s, and reports whether there
+// are files among them. Structs in seen are skipped like Router.params
+// does.
+func formProperties(g *schemaGen, s Schema, t reflect.Type, prefix string, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	props := s["properties"].(map[string]interface{})
+	multipart := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer && !isLeaf(ft) {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && len(f.Tag.Get("form")) == 0 && !isType(f.Type) {
+			if ft.Kind() == reflect.Struct {
+				multipart = formProperties(g, s, ft, prefix, seen) || multipart
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if key := strings.TrimSuffix(f.Tag.Get("file"), "?"); len(key) > 0 {
+			file := Schema{"type": "string", "format": "binary"}
+			if ft.Kind() == reflect.Slice {
+				props[prefix+key] = Schema{"type": "array", "items": file}
+			} else {
+				props[prefix+key] = file
+			}
+			multipart = true
+			continue
+		}
+		key := strings.TrimSuffix(f.Tag.Get("form"), "?")
+		if len(key) == 0 {
+			continue
+		}
+		if !isLeaf(ft) {
+			if ft.Kind() == reflect.Struct {
+				multipart = formProperties(g, s, ft, prefix+key+".", seen) || multipart
+			}
+			continue
+		}
+		props[prefix+key] = g.paramSchema(ft)
+	}
+	return multipart
+}
+
+// JSON returns the document encoded in JSON.
+func (d *OpenAPI) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML returns the document encoded in YAML.
+func (d *OpenAPI) YAML() ([]byte, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeYAMLMap(&buf, v, 0, false)
+	return buf.Bytes(), nil
+}
+
+// writeYAMLMap writes the mapping m in block style indented by indent,
+// whose first key follows what is written already if inline is true.
+func writeYAMLMap(buf *bytes.Buffer, m map[string]interface{}, indent int, inline bool) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i > 0 || !inline {
+			buf.WriteString(strings.Repeat(" ", indent))
+		}
+		buf.WriteString(yamlString(k))
+		buf.WriteByte(':')
+		writeYAMLValue(buf, m[k], indent+2)
+	}
+}
+
+// writeYAMLValue writes v following a key or a dash, with its lines
+// indented by indent if it is a non-empty collection.
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+		buf.WriteByte('\n')
+		writeYAMLMap(buf, v, indent, false)
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+		buf.WriteByte('\n')
+		for _, e := range v {
+			buf.WriteString(strings.Repeat(" ", indent))
+			if m, ok := e.(map[string]interface{}); ok && len(m) > 0 {
+				buf.WriteString("- ")
+				writeYAMLMap(buf, m, indent+2, true)
+				continue
+			}
+			buf.WriteByte('-')
+			writeYAMLValue(buf, e, indent+2)
+		}
+	case string:
+		buf.WriteString(" " + yamlString(v) + "\n")
+	case json.Number:
+		buf.WriteString(" " + v.String() + "\n")
+	case bool:
+		buf.WriteString(" " + strconv.FormatBool(v) + "\n")
+	default:
+		buf.WriteString(" null\n")
+	}
+}
+
+var yamlPlainRegexp = regexp.MustCompile(`^[A-Za-z_/$]([A-Za-z0-9_./{}+ -]*[A-Za-z0-9_./{}+-])?$`)
+
+// yamlString returns s as a plain scalar if it can be read back as the
+// same string, otherwise double-quoted.
+func yamlString(s string) string {
+	if yamlPlainRegexp.MatchString(s) {
+		switch strings.ToLower(s) {
+		case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+		default:
+			return s
+		}
+	}
+	return strconv.Quote(s)
+}
+
+//go:embed openapi.html
+var openAPIViewer string
+
+var openAPIViewerTemplate = template.Must(template.New("openapi").Parse(openAPIViewer))
+
+// ServeOpenAPI serves the document returned by OpenAPI at route followed
+// by /openapi.json and /openapi.yaml, and a page viewing it at route. The
+// document is made when it is first requested, so that it has the routes
+// registered after ServeOpenAPI as well, but not the routes it serves.
+func (r *Router) ServeOpenAPI(route string, info Info) {
+	var (
+		once     sync.Once
+		js, yaml []byte
+		err      error
+	)
+	document := func() error {
+		once.Do(func() {
+			doc := r.OpenAPI(info)
+			if js, err = doc.JSON(); err == nil {
+				yaml, err = doc.YAML()
+			}
+		})
+		return err
+	}
+	serve := func(ctype string, b *[]byte) HandlerFunc {
+		return func(c *Ctx) error {
+			if err := document(); err != nil {
+				return err
+			}
+			if err := c.commit(http.StatusOK, ctype); err != nil {
+				return err
+			}
+			_, err := c.rw.Write(*b)
+			return err
+		}
+	}
+
+	var page bytes.Buffer
+	spec := path.Join("/", r.prefix, route, "openapi.json")
+	if err := openAPIViewerTemplate.Execute(&page, spec); err != nil {
+		panic(err)
+	}
+	html := page.Bytes()
+	r.handle(http.MethodGet, route, serve("text/html; charset=utf-8", &html))
+	r.handle(http.MethodGet, path.Join(route, "openapi.json"), serve("application/json", &js))
+	r.handle(http.MethodGet, path.Join(route, "openapi.yaml"), serve("application/yaml", &yaml))
+}
@@ -0,0 +1,222 @@
+package hr
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type filter struct {
+	Name string  `query:"name?"`
+	And  *filter `query:"and?"`
+	Form *filter `form:"form?"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	type paging struct {
+		Page int `query:"page" default:"1"`
+	}
+	type listUsers struct {
+		paging
+		Org    UUID     `path:"org"`
+		IDs    []int    `query:"ids?" collection:"csv"`
+		Tags   []string `query:"tags?"`
+		Filter struct {
+			Name string `query:"name?"`
+		} `query:"filter"`
+		Tenant string   `header:"X-Tenant"`
+		Refs   []string `query:"refs?" collection:"brackets"`
+		Days   []int    `query:"days?" collection:"indexed" validate:"max=7"`
+		And    *filter  `query:"and?"`
+	}
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type createUser struct {
+		Org    UUID   `path:"org"`
+		Tenant string `header:"X-Tenant"`
+		Name   string `json:"name"`
+		Admin  bool
+	}
+	type upload struct {
+		Title  string                `form:"title"`
+		Cover  *multipart.FileHeader `file:"cover"`
+		Filter filter                `form:"filter"`
+	}
+
+	r := Default()
+	r.GET("/ping", func(c *Ctx) error { return nil })
+	g := r.Prefix("/orgs/:org")
+	g.Handle(http.MethodGet, "/users", Typed(func(c *Ctx, in listUsers) ([]user, error) { return nil, nil }))
+	g.Handle(http.MethodPost, "/users", TypedStatus(http.StatusCreated, func(c *Ctx, in createUser) (user, error) {
+		return user{}, nil
+	}))
+	g.Handle(http.MethodDelete, "/users/:id", TypedStatus(http.StatusNoContent, func(c *Ctx, in struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}))
+	r.Handle(http.MethodPut, "/covers", Typed(func(c *Ctx, in upload) (struct{}, error) { return struct{}{}, nil }))
+
+	doc := r.OpenAPI(Info{Title: "Users", Version: "1.0"})
+	b, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(keys ...string) interface{} {
+		var v interface{} = got
+		for _, k := range keys {
+			switch w := v.(type) {
+			case map[string]interface{}:
+				v = w[k]
+			case []interface{}:
+				i := 0
+				for ; i < len(w); i++ {
+					if w[i].(map[string]interface{})["name"] == k {
+						break
+					}
+				}
+				if i == len(w) {
+					return nil
+				}
+				v = w[i]
+			default:
+				return nil
+			}
+		}
+		return v
+	}
+
+	cases := []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"openapi"}, `"3.1.0"`},
+		{[]string{"info"}, `{"title":"Users","version":"1.0"}`},
+		{[]string{"paths", "/ping", "get", "responses", "default"},
+			`{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}},"description":"Error"}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "org"},
+			`{"in":"path","name":"org","required":true,"schema":{"format":"uuid","type":"string"}}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "page"},
+			`{"in":"query","name":"page","schema":{"default":1,"format":"int64","type":"integer"}}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "ids"},
+			`{"explode":false,"in":"query","name":"ids","schema":{"items":{"format":"int64","type":"integer"},"type":"array"},"style":"form"}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "tags"},
+			`{"explode":true,"in":"query","name":"tags","schema":{"items":{"type":"string"},"type":"array"},"style":"form"}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "filter.name"},
+			`{"in":"query","name":"filter.name","schema":{"type":"string"}}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "X-Tenant"},
+			`{"in":"header","name":"X-Tenant","required":true,"schema":{"type":"string"}}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "requestBody"}, `null`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "responses", "200", "content", "application/json", "schema"},
+			`{"items":{"$ref":"#/components/schemas/user"},"type":"array"}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "responses", "200", "content", "application/xml"}, `{}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "refs[]"},
+			`{"explode":true,"in":"query","name":"refs[]","schema":{"items":{"type":"string"},"type":"array"},"style":"form"}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "days"},
+			`{"explode":true,"in":"query","name":"days","schema":{"additionalProperties":{"format":"int64","type":"integer"},"maxProperties":7,` +
+				`"propertyNames":{"pattern":"^(0|[1-9][0-9]*)$"},"type":"object"},"style":"deepObject"}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "and.name"},
+			`{"in":"query","name":"and.name","schema":{"type":"string"}}`},
+		{[]string{"paths", "/orgs/{org}/users", "get", "parameters", "and.and.name"}, `null`},
+		{[]string{"paths", "/orgs/{org}/users", "post", "requestBody", "content", "application/json", "schema"},
+			`{"properties":{"Admin":{"type":"boolean"},"name":{"type":"string"}},"type":"object"}`},
+		{[]string{"paths", "/orgs/{org}/users", "post", "requestBody", "content", "application/cbor"}, `{}`},
+		{[]string{"paths", "/orgs/{org}/users", "post", "responses", "201", "description"}, `"Created"`},
+		{[]string{"paths", "/orgs/{org}/users/{id}", "delete", "parameters", "id"},
+			`{"in":"path","name":"id","required":true,"schema":{"type":"string"}}`},
+		{[]string{"paths", "/orgs/{org}/users/{id}", "delete", "responses", "204"}, `{"description":"No Content"}`},
+		{[]string{"paths", "/covers", "put", "requestBody", "content"},
+			`{"multipart/form-data":{"schema":{"properties":{"cover":{"format":"binary","type":"string"},"title":{"type":"string"}},"type":"object"}}}`},
+		{[]string{"components", "schemas", "user"},
+			`{"properties":{"id":{"format":"int64","type":"integer"},"name":{"type":"string"}},"type":"object"}`},
+		{[]string{"components", "schemas", "FieldError", "type"}, `"object"`},
+	}
+	for _, v := range cases {
+		b, _ := json.Marshal(lookup(v.keys...))
+		if string(b) != v.want {
+			t.Fatalf("%v: want %s got %s", v.keys, v.want, b)
+		}
+	}
+	if _, ok := got["paths"].(map[string]interface{})["/"]; ok {
+		t.Fatal("want no internal routes")
+	}
+}
+
+func TestOpenAPIYAML(t *testing.T) {
+	doc := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    Info{Title: "A: B", Version: "1"},
+		Paths: map[string]PathItem{"/a/{id}": {"get": &Operation{
+			Parameters: []Parameter{{Name: "id", In: "path", Required: true, Schema: Schema{"enum": []string{"yes", "b c"}}}},
+			Responses:  map[string]Response{"200": {Description: "OK", Content: map[string]MediaType{"text/plain": {Schema: Schema{}}}}},
+		}}},
+		Components: Components{Schemas: map[string]Schema{"X": {"$ref": "#/components/schemas/Y"}}},
+	}
+	b, err := doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `components:
+  schemas:
+    X:
+      $ref: "#/components/schemas/Y"
+info:
+  title: "A: B"
+  version: "1"
+openapi: "3.1.0"
+paths:
+  /a/{id}:
+    get:
+      parameters:
+        - in: path
+          name: id
+          required: true
+          schema:
+            enum:
+              - "yes"
+              - b c
+      responses:
+        "200":
+          content:
+            text/plain: {}
+          description: OK
+`
+	if string(b) != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, b)
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	r := New("/api")
+	r.ServeOpenAPI("/docs", Info{Title: "API", Version: "1"})
+	r.Handle(http.MethodGet, "/users/:id", Typed(func(c *Ctx, in struct {
+		ID int `path:"id"`
+	}) (string, error) {
+		return "", nil
+	}))
+
+	cases := []struct {
+		target, ctype, body string
+	}{
+		{"/api/docs", "text/html; charset=utf-8", `"/api/docs/openapi.json"`},
+		{"/api/docs/openapi.json", "application/json", `"/api/users/{id}": {`},
+		{"/api/docs/openapi.yaml", "application/yaml", "\n  /api/users/{id}:\n"},
+	}
+	for _, v := range cases {
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, v.target, nil))
+		if rw.Code != http.StatusOK || rw.Header().Get("Content-Type") != v.ctype || !strings.Contains(rw.Body.String(), v.body) {
+			t.Fatalf("%s: want %s %s got %d %s %s", v.target, v.ctype, v.body, rw.Code, rw.Header().Get("Content-Type"), rw.Body)
+		}
+		if strings.Contains(rw.Body.String(), "/docs/openapi") && v.ctype != "text/html; charset=utf-8" {
+			t.Fatalf("%s: want no documentation routes in %s", v.target, rw.Body)
+		}
+	}
+}
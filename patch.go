@@ -0,0 +1,434 @@
+package hr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonPatchType  = "application/json-patch+json"
+	mergePatchType = "application/merge-patch+json"
+)
+
+// BindPatch applies the patch in the request body to a copy of the value
+// v points to, validates the copy as Validate does, and sets v to it only
+// if it is valid. The patch is either a JSON Patch (RFC 6902) of media
+// type application/json-patch+json, or a JSON Merge Patch (RFC 7396) of
+// application/merge-patch+json. They are applied to the JSON encoding of
+// v, and the members of the result are set to the copy, so fields not
+// encoded in JSON, like those tagged with `json:"-"`, are kept as they
+// are, unless they are in slices or maps, which are replaced.
+//
+// Patches which cannot be applied, like those removing missing members,
+// result in 422 (unprocessable entity) responses, as well as those making
+// v invalid JSON of its type. A JSON Patch failing a test operation
+// results in a 409 (conflict) response.
+//
+// Example:
+//
+//	user, err := users.Get(c.Var("id"))
+//	if err != nil {
+//	    return err
+//	}
+//	if err := c.BindPatch(&user); err != nil {
+//	    return err
+//	}
+//	return users.Put(user)
+func (c *Ctx) BindPatch(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		panic(fmt.Sprintf("hr: BindPatch to non-pointer %T", v))
+	}
+	req := c.req
+	ctype := req.Header.Get("Content-Type")
+	mt, _, _ := mime.ParseMediaType(ctype)
+	if mt != jsonPatchType && mt != mergePatchType {
+		return UnsupportedMediaType("unsupported media type %q, accepted: %s, %s", ctype, jsonPatchType, mergePatchType)
+	}
+
+	opts := DecodeOptions{UseNumber: true}
+	if c.decoding != nil {
+		opts = *c.decoding
+		opts.UseNumber = true
+	}
+	if opts.MaxBodySize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(&c.rw, req.Body, opts.MaxBodySize)
+	}
+	defer req.Body.Close()
+	var patch interface{}
+	if err := (jsonCodec{}).decode(req.Body, &patch, &opts); err != nil {
+		return bodyError(err)
+	}
+
+	doc, err := jsonDocument(v)
+	if err != nil {
+		return err
+	}
+	if mt == mergePatchType {
+		doc = mergePatch(doc, patch)
+	} else if doc, err = applyPatch(doc, patch); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	decoded := reflect.New(rv.Elem().Type())
+	strict := &DecodeOptions{DisallowUnknownFields: opts.DisallowUnknownFields}
+	if err := (jsonCodec{}).decode(bytes.NewReader(b), decoded.Interface(), strict); err != nil {
+		e := UnprocessableEntity("invalid patched value: %v", err)
+		var terr *json.UnmarshalTypeError
+		if errors.As(err, &terr) && len(terr.Field) > 0 {
+			e.fields = &BindError{Fields: []FieldError{{Source: "body", Key: terr.Field, Message: "invalid value", err: err}}}
+		}
+		return e
+	}
+	copied := reflect.New(rv.Elem().Type())
+	copied.Elem().Set(rv.Elem())
+	setJSONMembers(copied.Elem(), decoded.Elem())
+	if err := c.validate(copied.Interface(), &BindError{}); err != nil {
+		return err
+	}
+	rv.Elem().Set(copied.Elem())
+	return nil
+}
+
+// setJSONMembers sets the fields of dst encoded in JSON to those of src,
+// keeping the others, if dst is a struct, otherwise sets dst to src.
+// Structs pointed to by dst are copied before they are set, so that the
+// values dst is copied from are left untouched.
+func setJSONMembers(dst, src reflect.Value) {
+	if !jsonStruct(dst.Type()) {
+		if dst.CanSet() {
+			dst.Set(src)
+		}
+		return
+	}
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("json") == "-" || !f.IsExported() && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		df, sf := dst.Field(i), src.Field(i)
+		if f.Type.Kind() == reflect.Pointer && jsonStruct(f.Type.Elem()) && !df.IsNil() && !sf.IsNil() && df.CanSet() {
+			p := reflect.New(f.Type.Elem())
+			p.Elem().Set(df.Elem())
+			setJSONMembers(p.Elem(), sf.Elem())
+			df.Set(p)
+			continue
+		}
+		setJSONMembers(df, sf)
+	}
+}
+
+// jsonStruct reports whether t is a struct encoded in JSON by its fields.
+func jsonStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !implements(t, jsonUnmarshalerType) && !implements(t, textUnmarshalerType)
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// jsonDocument returns the JSON encoding of v decoded to interface{},
+// with numbers kept as json.Number.
+func jsonDocument(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc interface{}
+	err = dec.Decode(&doc)
+	return doc, err
+}
+
+// mergePatch applies the JSON Merge Patch patch to doc, see RFC 7396.
+func mergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = make(map[string]interface{}, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+		} else {
+			d[k] = mergePatch(d[k], v)
+		}
+	}
+	return d
+}
+
+// patchError returns an hr.Error resulting in a 422 (unprocessable entity)
+// response of the i-th operation of a JSON Patch.
+func patchError(i int, format string, v ...interface{}) Error {
+	return UnprocessableEntity("invalid patch operation %d: %s", i, fmt.Sprintf(format, v...))
+}
+
+// applyPatch applies the JSON Patch patch to doc, see RFC 6902.
+func applyPatch(doc, patch interface{}) (interface{}, error) {
+	ops, ok := patch.([]interface{})
+	if !ok {
+		return nil, UnprocessableEntity("invalid patch: not an array of operations")
+	}
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, patchError(i, "not an object")
+		}
+		name, _ := op["op"].(string)
+		path, err := opPointer(op, "path")
+		if err != nil {
+			return nil, patchError(i, "%v", err)
+		}
+		value, hasValue := op["value"]
+
+		switch name {
+		case "add", "replace", "test":
+			if !hasValue {
+				return nil, patchError(i, "missing value")
+			}
+		case "move", "copy":
+			from, err := opPointer(op, "from")
+			if err != nil {
+				return nil, patchError(i, "%v", err)
+			}
+			if name == "move" && len(path) > len(from) && isPrefix(from, path) {
+				return nil, patchError(i, "move to a child of %s", op["from"])
+			}
+			if name == "move" {
+				doc, value, err = removeValue(doc, from)
+			} else if value, err = getValue(doc, from); err == nil {
+				value, err = deepCopy(value)
+			}
+			if err != nil {
+				return nil, patchError(i, "%v", err)
+			}
+		case "remove":
+		default:
+			return nil, patchError(i, "unknown op %q", op["op"])
+		}
+
+		switch name {
+		case "add", "move", "copy":
+			doc, err = addValue(doc, path, value)
+		case "remove":
+			doc, _, err = removeValue(doc, path)
+		case "replace":
+			if _, err = getValue(doc, path); err == nil {
+				if doc, _, err = removeValue(doc, path); err == nil {
+					doc, err = addValue(doc, path, value)
+				}
+			}
+		case "test":
+			var got interface{}
+			if got, err = getValue(doc, path); err == nil && !jsonEqual(got, value) {
+				return nil, Conflict("patch test failed at %s", op["path"])
+			}
+		}
+		if err != nil {
+			return nil, patchError(i, "%v", err)
+		}
+	}
+	return doc, nil
+}
+
+// opPointer returns the JSON Pointer of the member key of a JSON Patch
+// operation split into reference tokens, see RFC 6901.
+func opPointer(op map[string]interface{}, key string) ([]string, error) {
+	s, ok := op[key].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing %s", key)
+	}
+	if len(s) == 0 {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("bad pointer %q", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, tokens []string) bool {
+	for i, t := range prefix {
+		if tokens[i] != t {
+			return false
+		}
+	}
+	return true
+}
+
+// update replaces the container in doc at the pointer tokens but the last
+// with what fn returns of it and the last token, and returns doc updated.
+func update(doc interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	child, err := getValue(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = update(child, tokens[1:], fn); err != nil {
+		return nil, err
+	}
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		d[tokens[0]] = child
+	case []interface{}:
+		i, _ := arrayIndex(tokens[0], len(d))
+		d[i] = child
+	}
+	return doc, nil
+}
+
+// arrayIndex returns the index given by the token of an array of length n,
+// which may be n itself for "-".
+func arrayIndex(token string, n int) (int, error) {
+	if token == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || strings.Trim(token, "0123456789") != "" || len(token) > 1 && token[0] == '0' {
+		return 0, fmt.Errorf("bad array index %q", token)
+	}
+	return i, nil
+}
+
+func getValue(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(d))
+			if err != nil {
+				return nil, err
+			}
+			if i >= len(d) {
+				return nil, fmt.Errorf("index %s out of range", t)
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("no member %q of a scalar", t)
+		}
+	}
+	return doc, nil
+}
+
+func addValue(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return update(doc, tokens, func(parent interface{}, t string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[t] = value
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(t, len(p))
+			if err != nil {
+				return nil, err
+			}
+			if i > len(p) {
+				return nil, fmt.Errorf("index %s out of range", t)
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("no member %q of a scalar", t)
+	})
+}
+
+func removeValue(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err := update(doc, tokens, func(parent interface{}, t string) (interface{}, error) {
+		v, err := getValue(parent, []string{t})
+		if err != nil {
+			return nil, err
+		}
+		removed = v
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			delete(p, t)
+			return p, nil
+		case []interface{}:
+			i, _ := arrayIndex(t, len(p))
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return parent, nil
+	})
+	return doc, removed, err
+}
+
+func deepCopy(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return jsonDocument(json.RawMessage(b))
+}
+
+// jsonEqual reports whether the JSON values a and b are equal, where
+// numbers are equal if their values are.
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okx := new(big.Rat).SetString(a.String())
+		y, oky := new(big.Rat).SetString(b.String())
+		return okx && oky && x.Cmp(y) == 0
+	}
+	return a == b
+}
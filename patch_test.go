@@ -0,0 +1,104 @@
+package hr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBindPatch(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Geo  string `json:"-"`
+	}
+	type user struct {
+		Name    string            `json:"name" validate:"required"`
+		Age     int               `json:"age" validate:"min=0"`
+		Tags    []string          `json:"tags"`
+		Home    *address          `json:"home,omitempty"`
+		Labels  map[string]string `json:"labels,omitempty"`
+		Version int64             `json:"version"`
+		Hash    string            `json:"-"`
+		secret  string
+	}
+	original := user{
+		Name:    "gopher",
+		Age:     13,
+		Tags:    []string{"a", "b"},
+		Home:    &address{City: "Paris", Geo: "48.8,2.3"},
+		Labels:  map[string]string{"x": "1", "y": "2"},
+		Version: 9007199254740993,
+		Hash:    "h",
+		secret:  "s",
+	}
+
+	var got user
+	r := Default()
+	r.PATCH("/user", func(c *Ctx) error {
+		got = original
+		got.Tags = append([]string(nil), original.Tags...)
+		home := *original.Home
+		got.Home = &home
+		got.Labels = map[string]string{"x": "1", "y": "2"}
+		return c.BindPatch(&got)
+	})
+
+	cases := []struct {
+		ctype, body string
+		code        int
+		want        string
+	}{
+		{jsonPatchType, `[
+			{"op":"test","path":"/version","value":9007199254740993},
+			{"op":"replace","path":"/name","value":"gordon"},
+			{"op":"add","path":"/tags/1","value":"c"},
+			{"op":"add","path":"/tags/-","value":"d"},
+			{"op":"remove","path":"/labels/x"},
+			{"op":"copy","from":"/home/city","path":"/labels/city"},
+			{"op":"move","from":"/tags/0","path":"/labels/first"},
+			{"op":"test","path":"/age","value":13.0}
+		]`, http.StatusOK,
+			`{"name":"gordon","age":13,"tags":["c","b","d"],"home":{"city":"Paris"},"labels":{"city":"Paris","first":"a","y":"2"},"version":9007199254740993}`},
+		{jsonPatchType, `[{"op":"add","path":"/labels/a~1b","value":"z"},{"op":"remove","path":"/home"}]`, http.StatusOK,
+			`{"name":"gopher","age":13,"tags":["a","b"],"labels":{"a/b":"z","x":"1","y":"2"},"version":9007199254740993}`},
+		{mergePatchType + "; charset=utf-8", `{"name":"gordon","home":{"city":null},"labels":{"x":null,"z":"3"},"tags":["q"]}`, http.StatusOK,
+			`{"name":"gordon","age":13,"tags":["q"],"home":{"city":""},"labels":{"y":"2","z":"3"},"version":9007199254740993}`},
+		{mergePatchType, `{"home":null,"labels":null}`, http.StatusOK,
+			`{"name":"gopher","age":13,"tags":["a","b"],"version":9007199254740993}`},
+		{jsonPatchType, `[{"op":"test","path":"/name","value":"gordon"},{"op":"replace","path":"/name","value":"x"}]`,
+			http.StatusConflict, "patch test failed at /name"},
+		{jsonPatchType, `[{"op":"remove","path":"/missing"}]`, http.StatusUnprocessableEntity, `operation 0: no member`},
+		{jsonPatchType, `[{"op":"replace","path":"/tags/5","value":"x"}]`, http.StatusUnprocessableEntity, "out of range"},
+		{jsonPatchType, `[{"op":"add","path":"/tags/01","value":"x"}]`, http.StatusUnprocessableEntity, "bad array index"},
+		{jsonPatchType, `[{"op":"move","from":"/home","path":"/home/city"}]`, http.StatusUnprocessableEntity, "child of /home"},
+		{jsonPatchType, `[{"op":"add","path":"/name"}]`, http.StatusUnprocessableEntity, "missing value"},
+		{jsonPatchType, `[{"op":"jump","path":"/name"}]`, http.StatusUnprocessableEntity, "unknown op"},
+		{jsonPatchType, `{"op":"add"}`, http.StatusUnprocessableEntity, "not an array"},
+		{jsonPatchType, `[{"op":"replace","path":"/age","value":"old"}]`, http.StatusUnprocessableEntity, `"key":"age"`},
+		{mergePatchType, `{"name":""}`, http.StatusBadRequest, "invalid field: name (required)"},
+		{mergePatchType, `{"age":-1}`, http.StatusBadRequest, "invalid field: age (min=0)"},
+		{mergePatchType, `{"name":`, http.StatusBadRequest, "unexpected EOF"},
+		{"application/json", `{"name":"x"}`, http.StatusUnsupportedMediaType, jsonPatchType},
+	}
+	for _, v := range cases {
+		req := httptest.NewRequest(http.MethodPatch, "/user", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", v.ctype)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		body := rw.Body.String()
+		if rw.Code == http.StatusOK {
+			b, _ := json.Marshal(got)
+			body = string(b)
+			if got.Hash != "h" || got.secret != "s" || got.Home != nil && got.Home.Geo != "48.8,2.3" {
+				t.Fatalf("%s: want fields not in JSON kept got %+v %+v", v.body, got, got.Home)
+			}
+		} else if got.Name != original.Name || len(got.Tags) != len(original.Tags) {
+			t.Fatalf("%s: want value untouched got %+v", v.body, got)
+		}
+		if rw.Code != v.code || !strings.Contains(body, v.want) {
+			t.Fatalf("%s %s: want %d %s got %d %s", v.ctype, v.body, v.code, v.want, rw.Code, body)
+		}
+	}
+}
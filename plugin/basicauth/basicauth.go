@@ -13,6 +13,11 @@ const (
 	realm  = "Restricted"
 )
 
+// UserKey is the key under which the authenticated username is stored
+// in the request context. Use hr.Value[string](c, basicauth.UserKey)
+// to get it in handlers.
+const UserKey = "basicauth.user"
+
 type Options struct {
 	// Realm is a string describing a protected area. A realm allows a
 	// server to partition up the areas it protects (if supported by a
@@ -45,6 +50,7 @@ func BasicAuth(opts Options) hr.Plugin {
 				if err := opts.Validate(c, cred[:i], cred[i+1:]); err != nil {
 					return err
 				}
+				c.Set(UserKey, cred[:i])
 				return next.ServeHTTP(c)
 			}
 			c.ResponseWriter().Header().Set("WWW-Authenticate", scheme+" realm="+opts.Realm)
@@ -0,0 +1,61 @@
+package hr
+
+import "net/http"
+
+// response wraps an http.ResponseWriter to keep track of the status
+// code, the number of bytes written and whether the response header
+// has been committed.
+type response struct {
+	http.ResponseWriter
+	status    int
+	size      int64
+	committed bool
+}
+
+func (w *response) reset(rw http.ResponseWriter) {
+	w.ResponseWriter = rw
+	w.status = 0
+	w.size = 0
+	w.committed = false
+}
+
+// WriteHeader sends the response header with the provided status code
+// if it has not been sent yet. Informational (1xx) headers except 101
+// (switching protocols) can be sent several times before the final one,
+// so they do not commit the response.
+func (w *response) WriteHeader(code int) {
+	if w.committed {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		return
+	}
+	w.status = code
+	w.committed = true
+}
+
+func (w *response) Write(p []byte) (int, error) {
+	if !w.committed {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying writer does.
+func (w *response) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.committed {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer. It is used by
+// http.ResponseController to reach features like hijacking.
+func (w *response) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
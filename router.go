@@ -38,6 +38,11 @@ type Router struct {
 	chunks  sync.Pool
 	context sync.Pool
 	vars    sync.Pool
+	codecs  []Codec
+	rules   map[string]Rule
+
+	collection string
+	cookieKeys []cookieKey
 }
 
 func Default(plugins ...Plugin) *Router {
@@ -46,10 +51,11 @@ func Default(plugins ...Plugin) *Router {
 
 func New(prefix string, plugins ...Plugin) *Router {
 	router := &Router{
-		vars:    sync.Pool{New: func() any { return make(Vars, 0, 32) }},
-		chunks:  sync.Pool{New: func() any { return make([][]byte, 0) }},
-		context: sync.Pool{New: func() any { return new(Ctx) }},
+		vars:   sync.Pool{New: func() any { return make(Vars, 0, 32) }},
+		chunks: sync.Pool{New: func() any { return make([][]byte, 0) }},
+		codecs: append([]Codec(nil), defaultCodecs...),
 	}
+	router.context.New = func() any { return &Ctx{router: router} }
 	group := Group{
 		prefix:  prefix,
 		plugins: plugins,
@@ -95,14 +101,19 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		ctx := r.context.Get().(*Ctx)
 		ctx.Context = req.Context()
 		ctx.req = req
-		ctx.rw = w
+		ctx.rw.reset(w)
 		ctx.vars = vars
 
-		if err := h.ServeHTTP(ctx); err != nil {
-			// TODO: we should log the error if failed to send the response
-			ParseError(err).WriteTo(w)
+		// an error can only be sent if nothing of the response has been
+		// sent, otherwise it would be mixed up with what the handler has
+		// already written.
+		if err := h.ServeHTTP(ctx); err != nil && !ctx.Committed() {
+			// TODO: we should log the error if failed to send the response,
+			// or if it is dropped because the response is committed.
+			ParseError(err).WriteTo(&ctx.rw)
 		}
 		// put ctx back to the context pool.
+		ctx.reset()
 		r.context.Put(ctx)
 	} else {
 		http.NotFound(w, req)
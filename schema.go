@@ -0,0 +1,408 @@
+package hr
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema, like {"type": "string", "format": "date"}.
+type Schema map[string]interface{}
+
+// Schemer is implemented by types describing their values in JSON Schema,
+// like implementations of Type whose values are of a format.
+type Schemer interface {
+	JSONSchema() Schema
+}
+
+var (
+	schemerType    = reflect.TypeOf((*Schemer)(nil)).Elem()
+	enumValuesType = reflect.TypeOf((*EnumValues)(nil)).Elem()
+)
+
+// knownSchemas are the schemas of types which are not described well by
+// their kinds.
+var knownSchemas = map[reflect.Type]Schema{
+	timeType:                         {"type": "string", "format": "date-time"},
+	reflect.TypeOf(Time{}):           {"type": "string", "format": "date-time"},
+	reflect.TypeOf(Date{}):           {"type": "string", "format": "date"},
+	reflect.TypeOf(UUID{}):           {"type": "string", "format": "uuid"},
+	reflect.TypeOf(URL{}):            {"type": "string", "format": "uri"},
+	reflect.TypeOf(Email("")):        {"type": "string", "format": "email"},
+	reflect.TypeOf(IP{}):             {"type": "string"},
+	reflect.TypeOf(CIDR{}):           {"type": "string"},
+	reflect.TypeOf(Base64Bytes{}):    {"type": "string", "contentEncoding": "base64"},
+	reflect.TypeOf(Decimal("")):      {"type": "number"},
+	reflect.TypeOf(time.Duration(0)): {"type": "integer", "format": "int64"},
+}
+
+// JSONSchema returns the JSON Schema 2020-12 of the values of the type of
+// v, which is usually a struct bound by Ctx.Bind. Fields are named by
+// their `json` tags, or by the tags of where they are bound from, like
+// `form` and `query`, which make them required unless they end with '?'
+// or have default values. Rules of `validate` tags are described by
+// keywords, for example
+//
+//	required       the field is required
+//	min, max, len  minLength, maxLength, minItems, maxItems, minimum and
+//	               maximum, depending on the type of the field
+//	oneof          enum
+//	regexp         pattern
+//	email, url     format
+//	uuid           format
+//	dive           applies the rest rules to the items of the field
+//
+// Types implementing Schemer describe themselves, and schemas of named
+// structs are put in $defs. The schema of nil allows any value.
+func JSONSchema(v interface{}) Schema {
+	s := Schema{"$schema": "https://json-schema.org/draft/2020-12/schema"}
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return s
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	g := newSchemaGen("#/$defs/")
+	g.bind, g.root = true, t
+
+	root := g.schema(t)
+	if root["$ref"] == "#" {
+		root = g.object(t, nil)
+	}
+	for k, v := range root {
+		s[k] = v
+	}
+	if len(g.defs) > 0 {
+		s["$defs"] = g.defs
+	}
+	return s
+}
+
+// schemaGen generates the schemas of types. Schemas of named structs are
+// put in defs, and referred to by $ref to ref followed by their names.
+type schemaGen struct {
+	ref   string
+	defs  map[string]Schema
+	names map[reflect.Type]string
+	bind  bool         // whether fields are named by bind tags as well.
+	root  reflect.Type // the type referred to by $ref to "#", if any.
+}
+
+func newSchemaGen(ref string) *schemaGen {
+	return &schemaGen{
+		ref:   ref,
+		defs:  make(map[string]Schema),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// schema returns the schema of values of type t encoded in JSON.
+func (g *schemaGen) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if s := selfSchema(t); s != nil {
+		return s
+	}
+	if s, ok := knownSchemas[t]; ok {
+		return s.clone()
+	}
+	if s := enumSchema(t); s != nil {
+		return s
+	}
+	if implements(t, textMarshalerType) {
+		return Schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == g.root {
+			return Schema{"$ref": "#"}
+		}
+		if len(t.Name()) == 0 {
+			return g.object(t, nil)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.define(t, g.name(t))
+		}
+		return Schema{"$ref": g.ref + name}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Interface:
+		return Schema{}
+	}
+	return kindSchema(t.Kind())
+}
+
+// define puts the schema of the struct type t in defs by name, and
+// returns name.
+func (g *schemaGen) define(t reflect.Type, name string) string {
+	g.names[t] = name
+	g.defs[name] = nil // taken before the fields refer to it.
+	g.defs[name] = g.object(t, nil)
+	return name
+}
+
+// object returns the schema of the struct type t, whose fields are
+// dropped if skip reports true of them.
+func (g *schemaGen) object(t reflect.Type, skip func(f reflect.StructField) bool) Schema {
+	props := make(map[string]interface{})
+	var required []string
+	g.properties(props, &required, t, skip, make(map[reflect.Type]bool))
+	s := Schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// properties adds the properties of the fields of the struct type t to
+// props, by the names encoding/json encodes them with, or by their bind
+// tags if g.bind is true. Names of required properties are appended to
+// required. Embedded structs are flattened unless they are in seen, which
+// holds the structs being flattened.
+func (g *schemaGen) properties(props map[string]interface{}, required *[]string, t reflect.Type, skip func(f reflect.StructField) bool, seen map[reflect.Type]bool) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || skip != nil && skip(f) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		var source string
+		var opt bool
+		if g.bind && len(name) == 0 {
+			name, source, opt = bindName(f)
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && len(name) == 0 && ft.Kind() == reflect.Struct {
+			g.properties(props, required, ft, skip, seen)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+
+		var s Schema
+		switch {
+		case source == "file":
+			s = Schema{"type": "string", "format": "binary"}
+			if ft.Kind() == reflect.Slice {
+				s = Schema{"type": "array", "items": s}
+			}
+		case len(source) > 0 && isLeaf(f.Type):
+			s = g.paramSchema(f.Type)
+		default:
+			s = g.schema(f.Type)
+		}
+		req := len(source) > 0 && !opt
+		if def, ok := f.Tag.Lookup("default"); ok && len(source) > 0 {
+			s["default"] = s.valueOf(def)
+			req = false
+		}
+		if rules := f.Tag.Get("validate"); len(rules) > 0 && s.validation(splitRules(rules)) {
+			req = true
+		}
+		props[name] = s
+		if req {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// bindTags are the tags binding fields from requests but `json`, in the
+// order of fieldSources.
+var bindTags = []string{"form", "file", "query", "header", "cookie", "path"}
+
+// bindName returns the key of f given by the first of its bind tags, the
+// tag and whether the key is optional.
+func bindName(f reflect.StructField) (key, tag string, opt bool) {
+	for _, tag := range bindTags {
+		if key = f.Tag.Get(tag); len(key) > 0 {
+			if opt = key[len(key)-1] == '?'; opt {
+				key = key[:len(key)-1]
+			}
+			return key, tag, opt
+		}
+	}
+	return "", "", false
+}
+
+var schemaNameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// name returns a name of the named type t unused in defs.
+func (g *schemaGen) name(t reflect.Type) string {
+	base := strings.Trim(schemaNameRegexp.ReplaceAllString(t.Name(), "_"), "_")
+	name := base
+	for i := 2; ; i++ {
+		if _, ok := g.defs[name]; !ok {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+// paramSchema returns the schema of a parameter bound to a field of type
+// t, which is a leaf of binding.
+func (g *schemaGen) paramSchema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if s := selfSchema(t); s != nil {
+		return s
+	}
+	if s, ok := knownSchemas[t]; ok {
+		return s.clone()
+	}
+	if s := enumSchema(t); s != nil {
+		return s
+	}
+	if isType(t) {
+		return Schema{"type": "string"}
+	}
+	if t.Kind() == reflect.Slice {
+		return Schema{"type": "array", "items": g.paramSchema(t.Elem())}
+	}
+	return kindSchema(t.Kind())
+}
+
+// selfSchema returns the schema of t if it implements Schemer, otherwise
+// nil.
+func selfSchema(t reflect.Type) Schema {
+	if !implements(t, schemerType) {
+		return nil
+	}
+	return reflect.New(t).Interface().(Schemer).JSONSchema().clone()
+}
+
+// enumSchema returns the schema of t if it implements EnumValues, like
+// Enum does, otherwise nil.
+func enumSchema(t reflect.Type) Schema {
+	if !t.Implements(enumValuesType) || t.Kind() != reflect.String {
+		return nil
+	}
+	values := reflect.Zero(t).Interface().(EnumValues).Values()
+	return Schema{"type": "string", "enum": values}
+}
+
+func kindSchema(k reflect.Kind) Schema {
+	switch k {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return Schema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return Schema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return Schema{"type": "number", "format": "double"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	}
+	return Schema{}
+}
+
+// valueOf returns v, which is given by a tag, as a value of the type of
+// the schema s.
+func (s Schema) valueOf(v string) interface{} {
+	switch s["type"] {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// boundKeywords are the keywords of lower and upper bounds of values of
+// the types of schemas.
+var boundKeywords = map[string][2]string{
+	"string":  {"minLength", "maxLength"},
+	"array":   {"minItems", "maxItems"},
+	"object":  {"minProperties", "maxProperties"},
+	"integer": {"minimum", "maximum"},
+	"number":  {"minimum", "maximum"},
+}
+
+// validation adds the keywords describing the validation rules to s, and
+// reports whether one of them is required. Rules which have no keywords,
+// like eqfield and those registered on routers, are ignored.
+func (s Schema) validation(rules []string) (required bool) {
+	for i, r := range rules {
+		name, param, _ := strings.Cut(r, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			typ, _ := s["type"].(string)
+			kw, ok := boundKeywords[typ]
+			n, err := strconv.ParseFloat(param, 64)
+			if !ok || err != nil {
+				continue
+			}
+			if name != "max" {
+				s[kw[0]] = n
+			}
+			if name != "min" {
+				s[kw[1]] = n
+			}
+		case "oneof":
+			enum := []interface{}{}
+			for _, p := range strings.Fields(param) {
+				enum = append(enum, s.valueOf(p))
+			}
+			s["enum"] = enum
+		case "regexp":
+			s["pattern"] = param
+		case "email":
+			s["format"] = "email"
+		case "url":
+			s["format"] = "uri"
+		case "uuid":
+			s["format"] = "uuid"
+		case "dive":
+			if items, ok := s["items"].(Schema); ok {
+				items.validation(rules[i+1:])
+			} else if items, ok := s["additionalProperties"].(Schema); ok {
+				items.validation(rules[i+1:])
+			}
+			return required
+		}
+	}
+	return required
+}
+
+func (s Schema) clone() Schema {
+	c := make(Schema, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
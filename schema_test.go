@@ -0,0 +1,92 @@
+package hr
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"testing"
+)
+
+type grade int
+
+func (*grade) Parse(s string) error { return nil }
+
+func (grade) JSONSchema() Schema {
+	return Schema{"type": "string", "pattern": "^[A-F]$"}
+}
+
+type schemaColors struct{}
+
+func (schemaColors) Values() []string { return []string{"red", "blue"} }
+
+func TestJSONSchema(t *testing.T) {
+	type address struct {
+		City string `json:"city" validate:"required"`
+		Zip  string `json:"zip,omitempty" validate:"len=5"`
+	}
+	type paging struct {
+		Page int `query:"page" default:"1"`
+	}
+	type signup struct {
+		paging
+		Name    string                `form:"name" validate:"min=2,max=32"`
+		Email   string                `form:"email" validate:"email"`
+		Age     *uint8                `form:"age?" validate:"min=18"`
+		Role    string                `form:"role" default:"user" validate:"oneof=user admin"`
+		Tags    []string              `query:"tags?" validate:"max=3,dive,len=4"`
+		Scores  map[string]int        `json:"scores" validate:"dive,max=100"`
+		Code    string                `json:"code" validate:"required,regexp=^[A-Z]+$"`
+		Grade   grade                 `form:"grade?"`
+		Color   Enum[schemaColors]    `form:"color?"`
+		Home    address               `json:"home"`
+		Avatar  *multipart.FileHeader `file:"avatar?"`
+		Referer *signup               `json:"referer,omitempty"`
+		Notes   string
+		secret  string
+	}
+
+	b, err := json.Marshal(JSONSchema(&signup{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"$defs":{"address":{"properties":{"city":{"type":"string"},"zip":{"maxLength":5,"minLength":5,"type":"string"}},"required":["city"],"type":"object"}},` +
+		`"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{` +
+		`"Notes":{"type":"string"},` +
+		`"age":{"minimum":18,"type":"integer"},` +
+		`"avatar":{"format":"binary","type":"string"},` +
+		`"code":{"pattern":"^[A-Z]+$","type":"string"},` +
+		`"color":{"enum":["red","blue"],"type":"string"},` +
+		`"email":{"format":"email","type":"string"},` +
+		`"grade":{"pattern":"^[A-F]$","type":"string"},` +
+		`"home":{"$ref":"#/$defs/address"},` +
+		`"name":{"maxLength":32,"minLength":2,"type":"string"},` +
+		`"page":{"default":1,"format":"int64","type":"integer"},` +
+		`"referer":{"$ref":"#"},` +
+		`"role":{"default":"user","enum":["user","admin"],"type":"string"},` +
+		`"scores":{"additionalProperties":{"format":"int64","maximum":100,"type":"integer"},"type":"object"},` +
+		`"tags":{"items":{"maxLength":4,"minLength":4,"type":"string"},"maxItems":3,"type":"array"}},` +
+		`"required":["name","email","code"],"type":"object"}`
+	if string(b) != want {
+		t.Fatalf("want %s\ngot  %s", want, b)
+	}
+
+	b, _ = json.Marshal(JSONSchema([]Date{}))
+	if want := `{"$schema":"https://json-schema.org/draft/2020-12/schema","items":{"format":"date","type":"string"},"type":"array"}`; string(b) != want {
+		t.Fatalf("want %s got %s", want, b)
+	}
+
+	b, _ = json.Marshal(JSONSchema(nil))
+	if want := `{"$schema":"https://json-schema.org/draft/2020-12/schema"}`; string(b) != want {
+		t.Fatalf("want %s got %s", want, b)
+	}
+
+	b, _ = json.Marshal(JSONSchema(schemaLoop{}))
+	if want := `{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"N":{"format":"int64","type":"integer"}},"type":"object"}`; string(b) != want {
+		t.Fatalf("want %s got %s", want, b)
+	}
+}
+
+// schemaLoop embeds itself, which is flattened once.
+type schemaLoop struct {
+	*schemaLoop
+	N int
+}
@@ -0,0 +1,66 @@
+package hr
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Typed returns a handler which binds the request to an In with
+// Ctx.BindAll, validates it, calls fn with it and sends the Out returned
+// with status code 200, encoded in the media type negotiated by
+// Ctx.Negotiate. Errors of binding and those returned by fn are returned
+// as they are, and nothing is sent if fn has sent the response by
+// itself. In must be a struct type, whose tags are checked by TypedStatus
+// rather than when the handler serves requests.
+//
+// The handler is registered with Handle, since it is not a HandlerFunc
+// accepted by GET and the like, which keeps In and Out known to OpenAPI.
+//
+// Example:
+//
+//	type GetUser struct {
+//	    ID int `path:"id"`
+//	}
+//
+//	r.Handle(http.MethodGet, "/users/:id", hr.Typed(func(c *hr.Ctx, in GetUser) (User, error) {
+//	    return users.Get(in.ID)
+//	}))
+func Typed[In, Out any](fn func(c *Ctx, in In) (Out, error)) Handler {
+	return TypedStatus(http.StatusOK, fn)
+}
+
+// TypedStatus is like Typed, but sends the Out returned with status code
+// code, like 201 (created). Nothing but the header is sent if code is
+// 204 (no content).
+func TypedStatus[In, Out any](code int, fn func(c *Ctx, in In) (Out, error)) Handler {
+	if t := reflect.TypeOf((*In)(nil)).Elem(); t.Kind() != reflect.Struct {
+		panic("hr: typed handler input of non-struct type " + t.String())
+	} else if err := checkTags(t); err != nil {
+		panic(err.Error())
+	}
+	return &typedHandler[In, Out]{fn: fn, code: code}
+}
+
+type typedHandler[In, Out any] struct {
+	fn   func(c *Ctx, in In) (Out, error)
+	code int
+}
+
+func (h *typedHandler[In, Out]) ServeHTTP(c *Ctx) error {
+	var in In
+	if err := c.BindAll(&in); err != nil {
+		return err
+	}
+	out, err := h.fn(c, in)
+	if err != nil || c.Committed() {
+		return err
+	}
+	if h.code == http.StatusNoContent {
+		return c.NoContent()
+	}
+	return c.Negotiate(h.code, out)
+}
+
+func (h *typedHandler[In, Out]) signature() (in, out reflect.Type, code int) {
+	return reflect.TypeOf((*In)(nil)).Elem(), reflect.TypeOf((*Out)(nil)).Elem(), h.code
+}
@@ -0,0 +1,108 @@
+package hr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	type getUser struct {
+		ID      int    `path:"id"`
+		Verbose bool   `query:"verbose?"`
+		Tenant  string `header:"X-Tenant"`
+	}
+	type createUser struct {
+		Tenant string `header:"X-Tenant"`
+		Name   string `json:"name" validate:"required"`
+	}
+	type user struct {
+		ID     int    `json:"id" xml:"id"`
+		Name   string `json:"name" xml:"name"`
+		Tenant string `json:"tenant,omitempty" xml:"tenant,omitempty"`
+	}
+
+	r := Default()
+	r.Handle(http.MethodGet, "/users/:id", Typed(func(c *Ctx, in getUser) (user, error) {
+		if in.ID != 1 {
+			return user{}, NotFound("no user %d", in.ID)
+		}
+		u := user{ID: in.ID, Name: "gopher"}
+		if in.Verbose {
+			u.Tenant = in.Tenant
+		}
+		return u, nil
+	}))
+	r.Handle(http.MethodPost, "/users", TypedStatus(http.StatusCreated, func(c *Ctx, in createUser) (*user, error) {
+		return &user{ID: 2, Name: in.Name, Tenant: in.Tenant}, nil
+	}))
+	r.Handle(http.MethodDelete, "/users/:id", TypedStatus(http.StatusNoContent, func(c *Ctx, in getUser) (struct{}, error) {
+		return struct{}{}, nil
+	}))
+	r.Handle(http.MethodGet, "/raw", Typed(func(c *Ctx, in struct{}) (user, error) {
+		return user{}, c.String(http.StatusAccepted, "raw")
+	}))
+
+	cases := []struct {
+		method, target, accept, body string
+		code                         int
+		resp                         string
+	}{
+		{"GET", "/users/1?verbose=true", "", "", http.StatusOK, `{"id":1,"name":"gopher","tenant":"acme"}`},
+		{"GET", "/users/1", "application/xml", "", http.StatusOK, `<user><id>1</id><name>gopher</name></user>`},
+		{"GET", "/users/2", "", "", http.StatusNotFound, "no user 2"},
+		{"GET", "/users/x", "", "", http.StatusBadRequest, "invalid value: id"},
+		{"GET", "/users/1", "text/csv", "", http.StatusNotAcceptable, "acceptable media types"},
+		{"POST", "/users", "", `{"name":"gordon"}`, http.StatusCreated, `{"id":2,"name":"gordon","tenant":"acme"}`},
+		{"POST", "/users", "", `{}`, http.StatusBadRequest, "invalid field: name (required)"},
+		{"DELETE", "/users/1", "", "", http.StatusNoContent, ""},
+		{"GET", "/raw", "", "", http.StatusAccepted, "raw"},
+	}
+	for _, v := range cases {
+		req := httptest.NewRequest(v.method, v.target, strings.NewReader(v.body))
+		req.Header.Set("X-Tenant", "acme")
+		if len(v.body) > 0 {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if len(v.accept) > 0 {
+			req.Header.Set("Accept", v.accept)
+		}
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code || !strings.Contains(rw.Body.String(), v.resp) || len(v.resp) == 0 && rw.Body.Len() > 0 {
+			t.Fatalf("%s %s: want %d %s got %d %s", v.method, v.target, v.code, v.resp, rw.Code, rw.Body)
+		}
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err == nil || !strings.Contains(err.(string), "unknown modifier") {
+				t.Fatalf("want panic with bad tags got %v", err)
+			}
+		}()
+		type bad struct {
+			Q string `query:"q" mod:"shout"`
+		}
+		Typed(func(c *Ctx, in bad) (int, error) { return 0, nil })
+	}()
+
+	func() {
+		defer func() {
+			if err := recover(); err == nil || !strings.Contains(err.(string), "no field Pass") {
+				t.Fatalf("want panic with bad validate tags got %v", err)
+			}
+		}()
+		type bad struct {
+			Confirm string `json:"confirm" validate:"eqfield=Pass"`
+		}
+		Typed(func(c *Ctx, in bad) (int, error) { return 0, nil })
+	}()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want panic with non-struct input")
+		}
+	}()
+	Typed(func(c *Ctx, in int) (int, error) { return in, nil })
+}
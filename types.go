@@ -1,10 +1,26 @@
 package hr
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
 	"net"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// Type is implemented by types of fields that are bound from strings.
+// Types implementing encoding.TextUnmarshaler are bound as well, and
+// Type takes precedence if a type implements both.
 type Type interface {
 	Parse(string) error
 }
@@ -38,6 +54,331 @@ func (d *Duration) Parse(s string) error {
 type IP net.IP
 
 func (ip *IP) Parse(s string) error {
-	*ip = IP(net.ParseIP(s))
+	p := net.ParseIP(s)
+	if p == nil {
+		return fmt.Errorf("invalid IP address %q", s)
+	}
+	*ip = IP(p)
+	return nil
+}
+
+func (ip IP) MarshalText() ([]byte, error) {
+	return net.IP(ip).MarshalText()
+}
+
+func (ip *IP) UnmarshalText(b []byte) error {
+	return ip.Parse(string(b))
+}
+
+// UUID is a UUID in the canonical form, like
+// 123e4567-e89b-12d3-a456-426614174000.
+type UUID [16]byte
+
+func (u *UUID) Parse(s string) error {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return fmt.Errorf("invalid UUID %q", s)
+	}
+	var p UUID
+	src := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(p[:], []byte(src)); err != nil {
+		return fmt.Errorf("invalid UUID %q", s)
+	}
+	*u = p
+	return nil
+}
+
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[:], u[:4])
+	b[8] = '-'
+	hex.Encode(b[9:], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(b []byte) error {
+	return u.Parse(string(b))
+}
+
+// Date is a calendar date in the form of 2006-01-02, which is kept as
+// the midnight of the date in UTC.
+type Date time.Time
+
+const dateLayout = "2006-01-02"
+
+func (d *Date) Parse(s string) error {
+	p, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return err
+	}
+	*d = Date(p)
+	return nil
+}
+
+func (d Date) String() string {
+	return time.Time(d).Format(dateLayout)
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(b []byte) error {
+	return d.Parse(string(b))
+}
+
+// URL is an absolute URL.
+type URL url.URL
+
+func (u *URL) Parse(s string) error {
+	p, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if len(p.Scheme) == 0 || len(p.Host) == 0 && len(p.Opaque) == 0 {
+		return fmt.Errorf("invalid absolute URL %q", s)
+	}
+	*u = URL(*p)
+	return nil
+}
+
+func (u URL) String() string {
+	return (*url.URL)(&u).String()
+}
+
+func (u URL) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *URL) UnmarshalText(b []byte) error {
+	return u.Parse(string(b))
+}
+
+// Email is a bare email address like gopher@example.com, without a
+// display name or angle brackets.
+type Email string
+
+func (e *Email) Parse(s string) error {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return fmt.Errorf("invalid email address %q", s)
+	}
+	*e = Email(s)
+	return nil
+}
+
+func (e *Email) UnmarshalText(b []byte) error {
+	return e.Parse(string(b))
+}
+
+// CIDR is an IP network in the CIDR notation, like 192.168.0.0/16 or
+// 2001:db8::/32.
+type CIDR netip.Prefix
+
+func (c *CIDR) Parse(s string) error {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return err
+	}
+	*c = CIDR(p)
 	return nil
 }
+
+// Prefix returns c as a netip.Prefix.
+func (c CIDR) Prefix() netip.Prefix {
+	return netip.Prefix(c)
+}
+
+func (c CIDR) String() string {
+	return netip.Prefix(c).String()
+}
+
+func (c CIDR) MarshalText() ([]byte, error) {
+	return netip.Prefix(c).MarshalText()
+}
+
+func (c *CIDR) UnmarshalText(b []byte) error {
+	return c.Parse(string(b))
+}
+
+// Base64Bytes are bytes encoded in base64. Both the standard and the URL
+// safe alphabets are accepted, with or without padding, and the standard
+// one with padding is used to encode.
+type Base64Bytes []byte
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding.Strict(),
+	base64.RawStdEncoding.Strict(),
+	base64.URLEncoding.Strict(),
+	base64.RawURLEncoding.Strict(),
+}
+
+func (b *Base64Bytes) Parse(s string) error {
+	// decoders ignore newlines, which we don't.
+	if strings.ContainsAny(s, "\r\n") {
+		return fmt.Errorf("invalid base64 %q", s)
+	}
+	for _, enc := range base64Encodings {
+		if p, err := enc.DecodeString(s); err == nil {
+			*b = p
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid base64 %q", s)
+}
+
+func (b Base64Bytes) MarshalText() ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(b)), nil
+}
+
+func (b *Base64Bytes) UnmarshalText(p []byte) error {
+	return b.Parse(string(p))
+}
+
+// CSV is a list of values separated by commas, like 1,2,3 of CSV[int].
+// Values are parsed as if they are the values of fields of type T, and
+// none of them can be empty.
+type CSV[T any] []T
+
+func (c *CSV[T]) Parse(s string) error {
+	if len(s) == 0 {
+		*c = CSV[T]{}
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	p := make(CSV[T], len(parts))
+	set := newSetter(reflect.TypeOf(p).Elem())
+	for i, part := range parts {
+		if len(part) == 0 {
+			return fmt.Errorf("empty value in list %q", s)
+		}
+		if err := set(part, reflect.ValueOf(&p[i]).Elem()); err != nil {
+			return fmt.Errorf("invalid value %q in list: %w", part, err)
+		}
+	}
+	*c = p
+	return nil
+}
+
+func (c CSV[T]) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	for i, v := range c {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if m, ok := any(v).(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+			continue
+		}
+		fmt.Fprint(&buf, v)
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *CSV[T]) UnmarshalText(b []byte) error {
+	return c.Parse(string(b))
+}
+
+// EnumValues is implemented by types listing the values of an Enum.
+type EnumValues interface {
+	Values() []string
+}
+
+// Enum is a string which is one of the values listed by E.
+//
+// Example:
+//
+//	type colors struct{}
+//
+//	func (colors) Values() []string { return []string{"red", "green", "blue"} }
+//
+//	type Query struct {
+//	    Color hr.Enum[colors] `query:"color"`
+//	}
+type Enum[E EnumValues] string
+
+// Values returns the values e can be.
+func (e Enum[E]) Values() []string {
+	var values E
+	return values.Values()
+}
+
+func (e *Enum[E]) Parse(s string) error {
+	values := e.Values()
+	for _, v := range values {
+		if v == s {
+			*e = Enum[E](s)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid value %q, must be one of %s", s, strings.Join(values, ", "))
+}
+
+func (e *Enum[E]) UnmarshalText(b []byte) error {
+	return e.Parse(string(b))
+}
+
+// Decimal is an exact decimal number kept as it is, like -12.50. It is
+// encoded as a number in JSON, and decoded from either a number or a
+// string.
+type Decimal string
+
+var decimalRegexp = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+func (d *Decimal) Parse(s string) error {
+	if !decimalRegexp.MatchString(s) {
+		return fmt.Errorf("invalid decimal %q", s)
+	}
+	*d = Decimal(s)
+	return nil
+}
+
+// Rat returns d as a big.Rat, or nil if d is not a valid decimal.
+func (d Decimal) Rat() *big.Rat {
+	r, ok := new(big.Rat).SetString(string(d))
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+func (d *Decimal) UnmarshalText(b []byte) error {
+	return d.Parse(string(b))
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	if !decimalRegexp.MatchString(string(d)) {
+		return nil, fmt.Errorf("invalid decimal %q", string(d))
+	}
+	return []byte(d), nil
+}
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		return d.Parse(s)
+	}
+	return d.Parse(string(b))
+}
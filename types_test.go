@@ -0,0 +1,126 @@
+package hr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type colors struct{}
+
+func (colors) Values() []string { return []string{"red", "green", "blue"} }
+
+func TestTypes(t *testing.T) {
+	cases := []struct {
+		v    Type
+		good []string
+		bad  []string
+	}{
+		{new(IP), []string{"127.0.0.1", "2001:db8::68"}, []string{"", "localhost", "1.2.3"}},
+		{new(UUID), []string{"123e4567-e89b-12d3-a456-426614174000", "123E4567-E89B-12D3-A456-426614174000"}, []string{"", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g"}},
+		{new(Date), []string{"2023-06-05", "2024-02-29"}, []string{"", "2023-6-5", "2023-02-29", "2023-06-05T00:00:00Z"}},
+		{new(URL), []string{"https://example.com/a?b=c", "mailto:gopher@example.com"}, []string{"", "/path", "example.com", "http://[::1"}},
+		{new(Email), []string{"gopher@example.com"}, []string{"", "gopher", "Gopher <gopher@example.com>", "<gopher@example.com>"}},
+		{new(CIDR), []string{"192.168.0.0/16", "2001:db8::/32"}, []string{"", "192.168.0.1", "192.168.0.0/33"}},
+		{new(Base64Bytes), []string{"aGk/Pz4+", "aGk_Pz4-", "aGk", "aGk="}, []string{"a", "aGk=\n", "aGk==", "!!"}},
+		{new(CSV[int]), []string{"", "1", "1,2,3"}, []string{"1,,2", "1, 2", "a"}},
+		{new(CSV[Date]), []string{"2023-06-05,2023-06-06"}, []string{"2023-06-05;2023-06-06"}},
+		{new(Enum[colors]), []string{"red", "blue"}, []string{"", "Red", "pink"}},
+		{new(Decimal), []string{"0", "-12.50", "12345678901234567890.123456789"}, []string{"", "+1", "01", "1.", ".5", "1e5", "NaN"}},
+	}
+	for _, c := range cases {
+		for _, s := range c.good {
+			if err := c.v.Parse(s); err != nil {
+				t.Fatalf("%T: want %q parsed got %v", c.v, s, err)
+			}
+		}
+		for _, s := range c.bad {
+			if err := c.v.Parse(s); err == nil {
+				t.Fatalf("%T: want %q rejected", c.v, s)
+			}
+		}
+	}
+}
+
+func TestTypesJSON(t *testing.T) {
+	type all struct {
+		IP     IP                 `json:"ip"`
+		UUID   UUID               `json:"uuid"`
+		Date   Date               `json:"date"`
+		URL    URL                `json:"url"`
+		Email  Email              `json:"email"`
+		CIDR   CIDR               `json:"cidr"`
+		Bytes  Base64Bytes        `json:"bytes"`
+		IDs    CSV[int]           `json:"ids"`
+		Color  Enum[colors]       `json:"color"`
+		Price  Decimal            `json:"price"`
+		Prices map[string]Decimal `json:"prices"`
+	}
+	const doc = `{"ip":"127.0.0.1","uuid":"123e4567-e89b-12d3-a456-426614174000","date":"2023-06-05",` +
+		`"url":"https://example.com/a","email":"gopher@example.com","cidr":"10.0.0.0/8","bytes":"aGk=",` +
+		`"ids":"1,2,3","color":"red","price":12.50,"prices":{"a":"0.10"}}`
+
+	var a all
+	if err := json.Unmarshal([]byte(doc), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Price != "12.50" || a.Prices["a"] != "0.10" || a.Price.Rat().FloatString(1) != "12.5" ||
+		!reflect.DeepEqual(a.IDs, CSV[int]{1, 2, 3}) || string(a.Bytes) != "hi" {
+		t.Fatalf("bad value %+v", a)
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"ip":"127.0.0.1","uuid":"123e4567-e89b-12d3-a456-426614174000","date":"2023-06-05",` +
+		`"url":"https://example.com/a","email":"gopher@example.com","cidr":"10.0.0.0/8","bytes":"aGk=",` +
+		`"ids":"1,2,3","color":"red","price":12.50,"prices":{"a":0.10}}`
+	if string(b) != want {
+		t.Fatalf("want %s\ngot  %s", want, b)
+	}
+	if s := fmt.Sprint(a.URL); s != "https://example.com/a" {
+		t.Fatalf("want URL printed as a string got %s", s)
+	}
+	if _, err := json.Marshal(Decimal("abc")); err == nil {
+		t.Fatal("want error encoding an invalid decimal")
+	}
+
+	for _, doc := range []string{`{"color":"pink"}`, `{"email":"gopher"}`, `{"price":"1e5"}`, `{"ids":"1,a"}`} {
+		if err := json.Unmarshal([]byte(doc), &a); err == nil {
+			t.Fatalf("want error decoding %s", doc)
+		}
+	}
+}
+
+func TestBindTypes(t *testing.T) {
+	type query struct {
+		ID    UUID         `query:"id"`
+		Since Date         `query:"since"`
+		Tags  CSV[string]  `query:"tags"`
+		Color Enum[colors] `query:"color"`
+		Min   *Decimal     `query:"min?"`
+	}
+	values := map[string][]string{
+		"id":    {"123e4567-e89b-12d3-a456-426614174000"},
+		"since": {"2023-06-05"},
+		"tags":  {"a,b"},
+		"color": {"green"},
+		"min":   {"9.99"},
+	}
+	var q query
+	if err := bind(&q, values, "query"); err != nil {
+		t.Fatal(err)
+	}
+	if q.ID.String() != values["id"][0] || q.Since.String() != "2023-06-05" ||
+		!reflect.DeepEqual(q.Tags, CSV[string]{"a", "b"}) || q.Color != "green" || q.Min == nil || *q.Min != "9.99" {
+		t.Fatalf("bad query %+v", q)
+	}
+
+	values["color"] = []string{"pink"}
+	values["min"] = []string{"cheap"}
+	err := bind(&q, values, "query")
+	if err == nil || err.Error() != "invalid value: color; invalid value: min" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+package hr
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+)
+
+// defaultMaxMemory is how many bytes of a multipart form are kept in
+// memory by default.
+const defaultMaxMemory = 32 << 20 // 32MiB
+
+// UploadLimits limits multipart forms parsed by Ctx.MultipartForm and
+// Ctx.Bind, or streamed by Ctx.StreamMultipart. Zero values mean no
+// limits, except MaxMemory.
+type UploadLimits struct {
+	// MaxMemory is how many bytes of the form are kept in memory, and the
+	// rest of files are stored in temporary files. It defaults to 32MiB.
+	MaxMemory int64
+	// MaxSize limits the size of the whole request body.
+	MaxSize int64
+	// MaxFileSize limits the size of each file, or of each part when the
+	// form is streamed by Ctx.StreamMultipart.
+	MaxFileSize int64
+	// MaxFiles limits the number of files.
+	MaxFiles int
+	// Types lists the media types files are allowed to be, like
+	// "image/png" or "image/*". Types of files are sniffed from their
+	// content by http.DetectContentType rather than trusting the client.
+	Types []string
+}
+
+// LimitUploads returns a plugin limiting multipart forms uploaded to the
+// routes it is applied to. Limits given to a route take precedence over
+// those given to the router or a group.
+//
+// Example:
+//
+//	r.POST("/avatar", setAvatar, hr.LimitUploads(hr.UploadLimits{
+//	    MaxFileSize: 1 << 20,
+//	    MaxFiles:    1,
+//	    Types:       []string{"image/png", "image/jpeg"},
+//	}))
+func LimitUploads(limits UploadLimits) Plugin {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(c *Ctx) error {
+			c.uploads = &limits
+			return next.ServeHTTP(c)
+		})
+	}
+}
+
+// MultipartForm returns parsed multipart form, including file uploads.
+// The form is limited by the limits given by LimitUploads, and violating
+// them results in a 413 (request entity too large) or, for files of types
+// not allowed, a 415 (unsupported media type) error.
+func (c *Ctx) MultipartForm() (*multipart.Form, error) {
+	limits := c.uploads
+	if limits == nil {
+		limits = &UploadLimits{}
+	}
+	maxMemory := limits.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMaxMemory
+	}
+
+	req := c.req
+	if req.MultipartForm == nil && limits.MaxSize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(&c.rw, req.Body, limits.MaxSize)
+	}
+	// we don't bother to check if the form is parsed or not
+	// because ParseMultipartForm will do it for us.
+	if err := req.ParseMultipartForm(maxMemory); err != nil {
+		var merr *http.MaxBytesError
+		if errors.As(err, &merr) || errors.Is(err, multipart.ErrMessageTooLarge) {
+			return nil, RequestEntityTooLarge("multipart form too large")
+		}
+		return nil, BadRequest(err.Error())
+	}
+	if err := limits.check(req.MultipartForm); err != nil {
+		return nil, err
+	}
+	return req.MultipartForm, nil
+}
+
+// check returns an hr.Error if files in form violates the limits.
+func (l *UploadLimits) check(form *multipart.Form) error {
+	n := 0
+	for key, fhs := range form.File {
+		n += len(fhs)
+		if l.MaxFiles > 0 && n > l.MaxFiles {
+			return RequestEntityTooLarge("too many files, at most %d", l.MaxFiles)
+		}
+		for _, fh := range fhs {
+			if l.MaxFileSize > 0 && fh.Size > l.MaxFileSize {
+				return RequestEntityTooLarge("file %q of %s too large, at most %d bytes", fh.Filename, key, l.MaxFileSize)
+			}
+			if len(l.Types) == 0 {
+				continue
+			}
+			typ, err := sniffFile(fh)
+			if err != nil {
+				return BadRequest(err.Error())
+			}
+			if !matchTypes(l.Types, typ) {
+				return UnsupportedMediaType("file %q of %s is of type %s, accepted: %s", fh.Filename, key, typ, strings.Join(l.Types, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// sniffFile returns the media type of the content of fh.
+func sniffFile(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := f.Read(buf)
+	if err != nil && n == 0 && fh.Size > 0 {
+		return "", err
+	}
+	typ, _, _ := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return typ, nil
+}
+
+// matchTypes reports whether the media type typ matches any of types,
+// which may be wildcards like image/* and */*.
+func matchTypes(types []string, typ string) bool {
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if t == typ || t == "*/*" ||
+			strings.HasSuffix(t, "/*") && strings.HasPrefix(typ, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// bindFiles binds files to the fields of the struct v points to tagged
+// with `file`, which are either *multipart.FileHeader or a slice of it.
+// As with bind, a key ending with '?' is optional.
+func bindFiles(v interface{}, files map[string][]*multipart.FileHeader) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Pointer || val.Elem().Kind() != reflect.Struct {
+		return errors.New("binding element must be a struct")
+	}
+	var errs BindError
+	if err := bindFileFields(val.Elem(), files, &errs); err != nil {
+		return err
+	}
+	return errs.err()
+}
+
+func bindFileFields(val reflect.Value, files map[string][]*multipart.FileHeader, errs *BindError) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fieldTyp := typ.Field(i)
+		fieldVal := val.Field(i)
+		key := fieldTyp.Tag.Get("file")
+
+		if fieldTyp.Anonymous && len(key) == 0 {
+			if fieldVal.Kind() == reflect.Pointer && !fieldVal.IsNil() {
+				fieldVal = fieldVal.Elem()
+			}
+			if fieldVal.Kind() == reflect.Struct {
+				if err := bindFileFields(fieldVal, files, errs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !fieldVal.CanSet() || len(key) == 0 {
+			continue
+		}
+
+		opt := key[len(key)-1] == '?'
+		if opt {
+			key = key[:len(key)-1]
+		}
+		fhs := files[key]
+		if len(fhs) == 0 {
+			if !opt {
+				errs.Fields = append(errs.Fields, FieldError{Source: "form", Key: key, Message: "missing field"})
+			}
+			continue
+		}
+		switch fieldTyp.Type {
+		case fileHeaderType:
+			fieldVal.Set(reflect.ValueOf(fhs[0]))
+		case fileHeadersType:
+			fieldVal.Set(reflect.ValueOf(fhs))
+		default:
+			return fmt.Errorf("unsupported type %s of file: %s", fieldTyp.Type, key)
+		}
+	}
+	return nil
+}
+
+// Part is a part of a multipart form streamed by Ctx.StreamMultipart.
+// Reading a part computes its SHA-256 checksum on the fly.
+type Part struct {
+	p *part
+}
+
+type part struct {
+	*multipart.Part
+	r     io.Reader
+	typ   string
+	limit int64
+	n     int64
+	sha   interface {
+		io.Writer
+		Sum([]byte) []byte
+	}
+}
+
+// Name returns the name of the form field the part belongs to.
+func (p Part) Name() string {
+	return p.p.FormName()
+}
+
+// FileName returns the name of the file the part holds, or an empty
+// string if the part is not a file.
+func (p Part) FileName() string {
+	return p.p.FileName()
+}
+
+// Header returns the header of the part.
+func (p Part) Header() textproto.MIMEHeader {
+	return p.p.Header
+}
+
+// ContentType returns the media type of the part, which is sniffed from
+// the content if the part is a file.
+func (p Part) ContentType() string {
+	return p.p.typ
+}
+
+// Read reads the content of the part. Errors reading it are hr.Errors
+// resulting in a 413 (request entity too large) response if the part or
+// the request body is too large, or a 400 (bad request) one otherwise.
+func (p Part) Read(b []byte) (int, error) {
+	pt := p.p
+	if pt.limit > 0 && int64(len(b)) > pt.limit-pt.n+1 {
+		// read one more byte to know if the part is too large.
+		b = b[:pt.limit-pt.n+1]
+	}
+	n, err := pt.r.Read(b)
+	if err != nil && err != io.EOF {
+		err = bodyError(err)
+	}
+	if pt.limit > 0 && pt.n+int64(n) > pt.limit {
+		n = int(pt.limit - pt.n)
+		err = RequestEntityTooLarge("part %s too large, at most %d bytes", pt.FormName(), pt.limit)
+	}
+	pt.n += int64(n)
+	pt.sha.Write(b[:n])
+	return n, err
+}
+
+// Size returns the number of bytes read from the part so far.
+func (p Part) Size() int64 {
+	return p.p.n
+}
+
+// SHA256 returns the SHA-256 checksum of the bytes read from the part
+// so far, which is the checksum of the part once it is read to the end.
+func (p Part) SHA256() []byte {
+	return p.p.sha.Sum(nil)
+}
+
+// StreamMultipart calls fn with every part of the multipart form in the
+// request body as the parts are read, without buffering them in memory
+// or in temporary files as MultipartForm does. The limits given by
+// LimitUploads are enforced, in which MaxFileSize limits every part and
+// MaxMemory is ignored. Errors violating the limits or reading a malformed
+// form are hr.Errors, and so are errors returned by fn if they wrap
+// those of reading parts.
+//
+// Example:
+//
+//	err := c.StreamMultipart(func(part hr.Part) error {
+//	    if part.FileName() == "" {
+//	        return nil
+//	    }
+//	    return store.Put(part.FileName(), part)
+//	})
+func (c *Ctx) StreamMultipart(fn func(part Part) error) error {
+	limits := c.uploads
+	if limits == nil {
+		limits = &UploadLimits{}
+	}
+	req := c.req
+	if limits.MaxSize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(&c.rw, req.Body, limits.MaxSize)
+	}
+	mr, err := req.MultipartReader()
+	if errors.Is(err, http.ErrNotMultipart) {
+		return UnsupportedMediaType("unsupported media type %q, accepted: multipart/form-data", req.Header.Get("Content-Type"))
+	}
+	if err != nil {
+		return BadRequest(err.Error())
+	}
+
+	files := 0
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return bodyError(err)
+		}
+
+		pt := &part{Part: p, r: p, typ: p.Header.Get("Content-Type"), sha: sha256.New(), limit: limits.MaxFileSize}
+		if len(p.FileName()) > 0 {
+			files++
+			if limits.MaxFiles > 0 && files > limits.MaxFiles {
+				return RequestEntityTooLarge("too many files, at most %d", limits.MaxFiles)
+			}
+			br := bufio.NewReaderSize(p, 512)
+			head, err := br.Peek(512)
+			if err != nil && err != io.EOF {
+				return bodyError(err)
+			}
+			pt.r = br
+			pt.typ, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+			if len(limits.Types) > 0 && !matchTypes(limits.Types, pt.typ) {
+				return UnsupportedMediaType("file %q of %s is of type %s, accepted: %s", p.FileName(), p.FormName(), pt.typ, strings.Join(limits.Types, ", "))
+			}
+		}
+
+		if err := fn(Part{pt}); err != nil {
+			var herr Error
+			if errors.As(err, &herr) {
+				return herr
+			}
+			return err
+		}
+	}
+}
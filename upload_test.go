@@ -0,0 +1,176 @@
+package hr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+type upload struct {
+	name, filename, content string
+}
+
+func multipartRequest(t *testing.T, fields map[string]string, files ...upload) *http.Request {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	for _, f := range files {
+		fw, err := w.CreateFormFile(f.name, f.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(f.content))
+	}
+	w.Close()
+	req, _ := http.NewRequest("POST", "/upload?album=summer", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestBindMultipart(t *testing.T) {
+	type form struct {
+		Title  string                  `form:"title" validate:"min=2"`
+		Album  string                  `form:"album"`
+		Cover  *multipart.FileHeader   `file:"cover"`
+		Photos []*multipart.FileHeader `file:"photos?"`
+	}
+
+	var got form
+	r := Default(LimitUploads(UploadLimits{MaxFiles: 3}))
+	r.POST("/upload", func(c *Ctx) error {
+		got = form{}
+		if err := c.Bind(&got); err != nil {
+			return err
+		}
+		return c.NoContent()
+	}, LimitUploads(UploadLimits{
+		MaxSize:     4 << 10,
+		MaxFileSize: 1 << 10,
+		MaxFiles:    2,
+		Types:       []string{"image/*"},
+	}))
+
+	req := multipartRequest(t, map[string]string{"title": "beach"},
+		upload{"cover", "a.png", pngHeader + "cover"},
+		upload{"photos", "b.png", pngHeader + "photo"},
+	)
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if rw.Code != http.StatusNoContent {
+		t.Fatalf("bad response %d %s", rw.Code, rw.Body)
+	}
+	if got.Title != "beach" || got.Album != "summer" || got.Cover == nil || got.Cover.Filename != "a.png" || len(got.Photos) != 1 {
+		t.Fatalf("bad form %+v", got)
+	}
+
+	big := strings.Repeat("x", 2<<10)
+	cases := []struct {
+		req  *http.Request
+		code int
+		body string
+	}{
+		{multipartRequest(t, map[string]string{"title": "x"}), http.StatusBadRequest, `"key":"cover","message":"missing field"`},
+		{multipartRequest(t, map[string]string{"title": "x"}), http.StatusBadRequest, `"key":"title","rule":"min=2"`},
+		{multipartRequest(t, nil, upload{"cover", "a.txt", "hello"}), http.StatusUnsupportedMediaType, "text/plain"},
+		{multipartRequest(t, nil, upload{"cover", "a.png", pngHeader + big}), http.StatusRequestEntityTooLarge, "too large"},
+		{multipartRequest(t, nil, upload{"cover", "a.png", pngHeader}, upload{"photos", "b.png", pngHeader}, upload{"photos", "c.png", pngHeader}), http.StatusRequestEntityTooLarge, "too many files"},
+		{multipartRequest(t, map[string]string{"title": big + big}), http.StatusRequestEntityTooLarge, "too large"},
+	}
+	for _, v := range cases {
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, v.req)
+		if rw.Code != v.code || !strings.Contains(rw.Body.String(), v.body) {
+			t.Fatalf("want %d %s got %d %s", v.code, v.body, rw.Code, rw.Body)
+		}
+	}
+}
+
+func TestMatchTypes(t *testing.T) {
+	types := []string{"image/*", "application/PDF"}
+	for typ, want := range map[string]bool{
+		"image/png":       true,
+		"application/pdf": true,
+		"imagex/png":      false,
+		"text/plain":      false,
+	} {
+		if got := matchTypes(types, typ); got != want {
+			t.Fatalf("%s: want %v got %v", typ, want, got)
+		}
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	type file struct {
+		name, filename, typ, sum string
+		size                     int64
+	}
+	var got []file
+	r := Default()
+	r.POST("/upload", func(c *Ctx) error {
+		got = nil
+		err := c.StreamMultipart(func(part Part) error {
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				return fmt.Errorf("store %s: %w", part.Name(), err)
+			}
+			got = append(got, file{part.Name(), part.FileName(), part.ContentType(), hex.EncodeToString(part.SHA256()), part.Size()})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return c.NoContent()
+	}, LimitUploads(UploadLimits{MaxFileSize: 64, MaxFiles: 2, Types: []string{"image/png"}}))
+
+	req := multipartRequest(t, map[string]string{"title": "beach"}, upload{"cover", "a.png", pngHeader})
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	sum := func(s string) string {
+		b := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(b[:])
+	}
+	want := []file{
+		{"title", "", "", sum("beach"), 5},
+		{"cover", "a.png", "image/png", sum(pngHeader), int64(len(pngHeader))},
+	}
+	if rw.Code != http.StatusNoContent || len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("bad response %d %s %+v", rw.Code, rw.Body, got)
+	}
+
+	big := strings.Repeat("x", 100)
+	cases := []struct {
+		req  *http.Request
+		code int
+		body string
+	}{
+		{multipartRequest(t, nil, upload{"cover", "a.png", pngHeader + big}), http.StatusRequestEntityTooLarge, "part cover too large"},
+		{multipartRequest(t, map[string]string{"title": big}), http.StatusRequestEntityTooLarge, "part title too large"},
+		{multipartRequest(t, nil, upload{"cover", "a.gif", "GIF89a"}), http.StatusUnsupportedMediaType, "image/gif"},
+		{multipartRequest(t, nil, upload{"a", "a.png", pngHeader}, upload{"b", "b.png", pngHeader}, upload{"c", "c.png", pngHeader}), http.StatusRequestEntityTooLarge, "too many files"},
+	}
+	for _, v := range cases {
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, v.req)
+		if rw.Code != v.code || !strings.Contains(rw.Body.String(), v.body) {
+			t.Fatalf("want %d %s got %d %s", v.code, v.body, rw.Code, rw.Body)
+		}
+	}
+
+	req, _ = http.NewRequest("POST", "/upload", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	if rw.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("bad status code %d", rw.Code)
+	}
+}
@@ -0,0 +1,636 @@
+package hr
+
+import (
+	"encoding"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Rule reports whether v is valid against a validation rule with the
+// parameter param, which is what follows '=' in the rule, for example
+// "3" of `validate:"min=3"`. Pointers are dereferenced before rules are
+// applied, and rules are not applied to nil pointers at all. A rule may
+// panic if param is malformed, which is a programming error, though the
+// parameters of the built-in rules are checked along with the tags.
+type Rule func(v reflect.Value, param string) bool
+
+// builtinRules are the rules available to every router. Besides them,
+// there are rules required, omitempty, eqfield and dive which are dealt
+// with by the validator itself.
+var builtinRules = map[string]Rule{
+	"min":    ruleMin,
+	"max":    ruleMax,
+	"len":    ruleLen,
+	"oneof":  ruleOneOf,
+	"regexp": ruleRegexp,
+	"email":  ruleEmail,
+	"url":    ruleURL,
+	"uuid":   ruleUUID,
+}
+
+// Rule registers a validation rule named name on the router, which can
+// be used in `validate` tags of structs bound in handlers of the router.
+// Built-in rules can be replaced as well.
+func (r *Router) Rule(name string, rule Rule) {
+	if r.rules == nil {
+		r.rules = make(map[string]Rule)
+	}
+	r.rules[name] = rule
+
+	// drop the validations compiled against the former rules.
+	validations.Range(func(k, _ interface{}) bool {
+		if k.(validationKey).router == r {
+			validations.Delete(k)
+		}
+		return true
+	})
+}
+
+// rule returns the validation rule named name, or nil if there is not,
+// and whether it is a built-in one.
+func (r *Router) rule(name string) (Rule, bool) {
+	if r != nil {
+		if rule, ok := r.rules[name]; ok {
+			return rule, false
+		}
+	}
+	rule := builtinRules[name]
+	return rule, rule != nil
+}
+
+// Validate validates the fields of the struct v points to against the
+// rules given by their `validate` tags, which are separated by commas.
+// Commas in rule parameters must be escaped as `\,`. Nested structs are
+// validated as well, including those in slices, arrays and maps. An
+// hr.Error resulting in a 400 (bad request) response is returned if any
+// field is invalid, listing all of the invalid fields. The rules are
+//
+//	required      the field must not be a zero value
+//	omitempty     skips the rest rules if the field is a zero value
+//	min=n, max=n  bounds of the length of strings, slices and maps, or
+//	              of the value of numbers
+//	len=n         the exact length of strings, slices and maps, or the
+//	              exact value of numbers
+//	oneof=a b c   the field must be one of the space-separated values
+//	regexp=re     the field must match the regular expression re
+//	email         the field must be an email address
+//	url           the field must be an absolute URL
+//	uuid          the field must be a UUID
+//	eqfield=F     the field must be equal to the field F of the struct
+//	dive          applies the rest rules to the elements of the field
+//
+// together with those registered on the router. The tags are checked
+// once per struct type, and an hr.Error resulting in a 500 (internal
+// server error) response is returned if they are bad.
+func (c *Ctx) Validate(v interface{}) error {
+	return bindError(validate(v, c.router))
+}
+
+func validate(v interface{}, r *Router) error {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	vd := validator{router: r}
+	vd.validateStruct(val, "", "body")
+	if vd.err != nil {
+		return InternalServerError("%v", vd.err)
+	}
+	return vd.errs.err()
+}
+
+// validation is how to validate a struct type, compiled from the
+// `validate` tags of its fields.
+type validation struct {
+	fields []validatedField
+	err    error // the first error in the tags of the fields, if any.
+}
+
+// validatedField is how to validate a field of a struct.
+type validatedField struct {
+	index  int
+	name   string // name of the field in requests, empty if embedded.
+	source string // where the field comes from, empty if unknown.
+	rules  []validationRule
+}
+
+// validationRule is a rule of a `validate` tag.
+type validationRule struct {
+	text  string // the rule as written, like "min=3".
+	name  string
+	param string
+	rule  Rule  // the rule named name, if it is not dealt with by validator.
+	other []int // index of the field compared by eqfield.
+}
+
+type validationKey struct {
+	typ    reflect.Type
+	router *Router
+}
+
+var validations sync.Map // map[validationKey]*validation
+
+// validationOf returns the validation of the struct type t against the
+// rules of the router r.
+func validationOf(t reflect.Type, r *Router) *validation {
+	key := validationKey{t, r}
+	if v, ok := validations.Load(key); ok {
+		return v.(*validation)
+	}
+	seen := make(map[reflect.Type]*validation)
+	v, _ := validations.LoadOrStore(key, compileValidation(t, r.rule, seen))
+	return v.(*validation)
+}
+
+// compileValidation compiles the validation of the struct type t, with
+// rules looked up by lookup. The names and the parameters of rules are
+// not checked if lookup is nil. Validations being compiled are kept in
+// seen, so that recursive types refer to them rather than being
+// compiled endlessly.
+func compileValidation(t reflect.Type, lookup func(string) (Rule, bool),
+	seen map[reflect.Type]*validation) *validation {
+	if v, ok := seen[t]; ok {
+		return v
+	}
+	v := &validation{}
+	seen[t] = v
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		vf := validatedField{index: i}
+		if !f.Anonymous {
+			vf.name, vf.source = fieldName(f, "")
+		}
+		if len(tag) > 0 {
+			rules, err := compileRules(t, f.Type, splitRules(tag), lookup)
+			if err != nil {
+				v.err = fmt.Errorf("hr: field %s of %s: %w", f.Name, t, err)
+				return v
+			}
+			vf.rules = rules
+		}
+		if err := checkNested(f.Type, lookup, seen); err != nil {
+			v.err = err
+			return v
+		}
+		v.fields = append(v.fields, vf)
+	}
+	return v
+}
+
+// compileRules compiles rules applied to a field of type t of the struct
+// type parent.
+func compileRules(parent, t reflect.Type, rules []string,
+	lookup func(string) (Rule, bool)) ([]validationRule, error) {
+	var vrs []validationRule
+	for i, r := range rules {
+		name, param, _ := strings.Cut(r, "=")
+		vr := validationRule{text: r, name: name, param: param}
+		switch name {
+		case "omitempty", "required":
+		case "eqfield":
+			other, ok := parent.FieldByName(param)
+			if !ok {
+				return nil, fmt.Errorf("no field %s for rule eqfield", param)
+			}
+			vr.other = other.Index
+		case "dive":
+			elem := t
+			for elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+			switch elem.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				elem = elem.Elem()
+			case reflect.Interface:
+			default:
+				return nil, fmt.Errorf("rule dive applied to %s", t)
+			}
+			rest, err := compileRules(parent, elem, rules[i+1:], lookup)
+			return append(append(vrs, vr), rest...), err
+		default:
+			if lookup == nil {
+				break
+			}
+			rule, builtin := lookup(name)
+			if rule == nil {
+				return nil, fmt.Errorf("unknown validation rule %q", name)
+			}
+			if check := ruleParams[name]; builtin && check != nil {
+				if err := check(t, param); err != nil {
+					return nil, fmt.Errorf("rule %s: %w", r, err)
+				}
+			}
+			vr.rule = rule
+		}
+		vrs = append(vrs, vr)
+	}
+	return vrs, nil
+}
+
+// checkNested compiles the validations of the structs which values of
+// type t are or hold, returning the first error in their tags.
+func checkNested(t reflect.Type, lookup func(string) (Rule, bool),
+	seen map[reflect.Type]*validation) error {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			return compileValidation(t, lookup, seen).err
+		default:
+			return nil
+		}
+	}
+}
+
+// validator validates a struct, collecting all of its invalid fields.
+type validator struct {
+	router *Router
+	errs   BindError
+	err    error // the first error in the tags of the structs, if any.
+}
+
+// fail records that the field keyed by key failed the rule.
+func (vd *validator) fail(key, source, rule string) {
+	vd.errs.Fields = append(vd.errs.Fields, FieldError{
+		Source:  source,
+		Key:     key,
+		Rule:    rule,
+		Message: "invalid field",
+	})
+}
+
+func (vd *validator) validateStruct(val reflect.Value, prefix, source string) {
+	v := validationOf(val.Type(), vd.router)
+	if v.err != nil {
+		if vd.err == nil {
+			vd.err = v.err
+		}
+		return
+	}
+	for _, f := range v.fields {
+		key, src := prefix, source
+		if len(f.name) > 0 {
+			key = joinKey(prefix, f.name)
+			if len(f.source) > 0 {
+				src = f.source
+			}
+		}
+		fv := val.Field(f.index)
+		if f.rules == nil {
+			vd.validateNested(fv, key, src)
+			continue
+		}
+		vd.validateValue(val, fv, key, src, f.rules)
+	}
+}
+
+// validateValue validates v, a field of the struct parent, against rules.
+// The rest rules are skipped once v fails one.
+func (vd *validator) validateValue(parent, v reflect.Value, key, source string,
+	rules []validationRule) {
+	for i, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if v.IsZero() {
+				return
+			}
+		case "required":
+			if !hasValue(v) {
+				vd.fail(key, source, r.text)
+				return
+			}
+		case "eqfield":
+			// other is invalid if it is in a nil embedded struct.
+			other, _ := parent.FieldByIndexErr(r.other)
+			a, b := indirect(v), indirect(other)
+			if a.IsValid() != b.IsValid() ||
+				a.IsValid() && !reflect.DeepEqual(a.Interface(), b.Interface()) {
+				vd.fail(key, source, r.text)
+				return
+			}
+		case "dive":
+			vd.dive(parent, v, key, source, rules[i+1:])
+			return
+		default:
+			d := indirect(v)
+			if !d.IsValid() {
+				continue
+			}
+			if !r.rule(d, r.param) {
+				vd.fail(key, source, r.text)
+				return
+			}
+		}
+	}
+	vd.validateNested(v, key, source)
+}
+
+// dive validates the elements of a slice, an array or a map against
+// rules. Other values, which are held by interfaces, are left alone.
+func (vd *validator) dive(parent, v reflect.Value, key, source string,
+	rules []validationRule) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			k := joinKey(key, strconv.Itoa(i))
+			vd.validateValue(parent, v.Index(i), k, source, rules)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			k := joinKey(key, fmt.Sprint(iter.Key().Interface()))
+			vd.validateValue(parent, iter.Value(), k, source, rules)
+		}
+	}
+}
+
+// validateNested validates v if it is a struct, or structs in v if it
+// is a slice, an array or a map.
+func (vd *validator) validateNested(v reflect.Value, key, source string) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		vd.validateStruct(v, key, source)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			vd.validateNested(v.Index(i), joinKey(key, strconv.Itoa(i)), source)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			k := joinKey(key, fmt.Sprint(iter.Key().Interface()))
+			vd.validateNested(iter.Value(), k, source)
+		}
+	}
+}
+
+// hasValue reports whether v is not a zero value, which means non-empty
+// for slices and maps.
+func hasValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() > 0
+	}
+	return !v.IsZero()
+}
+
+// indirect dereferences v until it is not a pointer or an interface. An
+// invalid value is returned if a nil one is met.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// fieldSources maps tags naming fields in requests to where the fields
+// come from.
+var fieldSources = [][2]string{
+	{"json", "body"},
+	{"form", "form"},
+	{"file", "form"},
+	{"query", "query"},
+	{"header", "header"},
+	{"cookie", "cookie"},
+	{"path", "path"},
+}
+
+// fieldName returns the name of f in requests and where f comes from,
+// which is given by the first tag of json, form, file, query, header,
+// cookie and path. If f is not tagged, it is named by itself and comes from
+// source.
+func fieldName(f reflect.StructField, source string) (string, string) {
+	for _, s := range fieldSources {
+		name, _, _ := strings.Cut(f.Tag.Get(s[0]), ",")
+		name = strings.TrimSuffix(name, "?")
+		if len(name) > 0 && name != "-" {
+			return name, s[1]
+		}
+	}
+	return f.Name, source
+}
+
+func joinKey(prefix, key string) string {
+	if len(prefix) == 0 {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// splitRules splits a validate tag by commas which are not escaped.
+func splitRules(tag string) []string {
+	var rules []string
+	var sb strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			sb.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			rules = append(rules, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(tag[i])
+		}
+	}
+	return append(rules, sb.String())
+}
+
+// compare compares the size of v with param, which is the length of
+// strings in runes, the length of slices, arrays and maps, or the value
+// of numbers. ok is false if v has no size or param is malformed.
+func compare(v reflect.Value, param string) (c int, ok bool) {
+	cmp := func(a, b float64) int {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.String:
+		n := utf8.RuneCountInString(v.String())
+		p, err := strconv.ParseInt(param, 10, 64)
+		return cmp(float64(n), float64(p)), err == nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		p, err := strconv.ParseInt(param, 10, 64)
+		return cmp(float64(v.Len()), float64(p)), err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch i := v.Int(); {
+		case i < p:
+			return -1, true
+		case i > p:
+			return 1, true
+		}
+		return 0, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch u := v.Uint(); {
+		case u < p:
+			return -1, true
+		case u > p:
+			return 1, true
+		}
+		return 0, true
+	case reflect.Float32, reflect.Float64:
+		p, err := strconv.ParseFloat(param, 64)
+		return cmp(v.Float(), p), err == nil
+	}
+	return 0, false
+}
+
+// ruleParams check the parameters of the built-in rules applied to
+// fields of type t.
+var ruleParams = map[string]func(t reflect.Type, param string) error{
+	"min":    checkSize,
+	"max":    checkSize,
+	"len":    checkSize,
+	"regexp": checkRegexp,
+}
+
+// checkSize checks the size param compared by compare.
+func checkSize(t reflect.Type, param string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var err error
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		_, err = strconv.ParseUint(param, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(param, 64)
+	default:
+		_, err = strconv.ParseInt(param, 10, 64)
+	}
+	return err
+}
+
+// checkRegexp compiles the regular expression param for ruleRegexp.
+func checkRegexp(_ reflect.Type, param string) error {
+	_, err := compileRegexp(param)
+	return err
+}
+
+func ruleMin(v reflect.Value, param string) bool {
+	c, ok := compare(v, param)
+	return ok && c >= 0
+}
+
+func ruleMax(v reflect.Value, param string) bool {
+	c, ok := compare(v, param)
+	return ok && c <= 0
+}
+
+func ruleLen(v reflect.Value, param string) bool {
+	c, ok := compare(v, param)
+	return ok && c == 0
+}
+
+func ruleOneOf(v reflect.Value, param string) bool {
+	s := valueString(v)
+	for _, p := range strings.Fields(param) {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+var regexps sync.Map // map[string]*regexp.Regexp
+
+// compileRegexp compiles the regular expression expr, which is compiled
+// only once.
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	v, _ := regexps.LoadOrStore(expr, re)
+	return v.(*regexp.Regexp), nil
+}
+
+func ruleRegexp(v reflect.Value, param string) bool {
+	re, err := compileRegexp(param)
+	return err == nil && re.MatchString(valueString(v))
+}
+
+func ruleEmail(v reflect.Value, _ string) bool {
+	s := valueString(v)
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func ruleURL(v reflect.Value, _ string) bool {
+	u, err := url.Parse(valueString(v))
+	return err == nil && len(u.Scheme) > 0 && (len(u.Host) > 0 || len(u.Opaque) > 0)
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func ruleUUID(v reflect.Value, _ string) bool {
+	return uuidRegexp.MatchString(valueString(v))
+}
+
+// valueString returns the text form of v.
+func valueString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	}
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			if b, err := x.MarshalText(); err == nil {
+				return string(b)
+			}
+		case fmt.Stringer:
+			return x.String()
+		}
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,174 @@
+package hr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateUser struct {
+	Name      string            `json:"name" validate:"required,min=2,max=8"`
+	Age       *int              `json:"age" validate:"omitempty,min=18"`
+	Role      string            `json:"role" validate:"oneof=admin user"`
+	Code      string            `json:"code" validate:"omitempty,regexp=^[a-z]{2\\,3}$"`
+	Email     string            `json:"email" validate:"email"`
+	Site      string            `json:"site" validate:"omitempty,url"`
+	ID        string            `json:"id" validate:"omitempty,uuid"`
+	Password  string            `json:"password" validate:"len=6"`
+	Confirm   string            `json:"confirm" validate:"eqfield=Password"`
+	Tags      []string          `json:"tags" validate:"max=3,dive,required,even"`
+	Addresses []validateAddress `json:"addresses"`
+	Meta      map[string]int    `json:"meta" validate:"dive,max=9"`
+}
+
+func validUser() validateUser {
+	age := 20
+	return validateUser{
+		Name:      "bob",
+		Age:       &age,
+		Role:      "admin",
+		Code:      "abc",
+		Email:     "bob@example.com",
+		Site:      "https://example.com",
+		ID:        "123e4567-e89b-12d3-a456-426614174000",
+		Password:  "secret",
+		Confirm:   "secret",
+		Tags:      []string{"aa", "bbbb"},
+		Addresses: []validateAddress{{City: "Paris"}},
+		Meta:      map[string]int{"k": 1},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	r := &Router{}
+	r.Rule("even", func(v reflect.Value, _ string) bool { return v.Len()%2 == 0 })
+
+	u := validUser()
+	if err := validate(&u, r); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		modify func(*validateUser)
+		err    string
+	}{
+		{func(u *validateUser) { u.Name = "" }, "invalid field: name (required)"},
+		{func(u *validateUser) { u.Name = "b" }, "invalid field: name (min=2)"},
+		{func(u *validateUser) { u.Name = "bobbobbob" }, "invalid field: name (max=8)"},
+		{func(u *validateUser) { age := 17; u.Age = &age }, "invalid field: age (min=18)"},
+		{func(u *validateUser) { u.Role = "root" }, "invalid field: role (oneof=admin user)"},
+		{func(u *validateUser) { u.Code = "a1" }, "invalid field: code (regexp=^[a-z]{2,3}$)"},
+		{func(u *validateUser) { u.Email = "Bob <bob@example.com>" }, "invalid field: email (email)"},
+		{func(u *validateUser) { u.Site = "/path" }, "invalid field: site (url)"},
+		{func(u *validateUser) { u.ID = "123" }, "invalid field: id (uuid)"},
+		{func(u *validateUser) { u.Password = "12345" }, "invalid field: password (len=6); invalid field: confirm (eqfield=Password)"},
+		{func(u *validateUser) { u.Confirm = "secreT" }, "invalid field: confirm (eqfield=Password)"},
+		{func(u *validateUser) { u.Tags = []string{"a", "b", "c", "d"} }, "invalid field: tags (max=3)"},
+		{func(u *validateUser) { u.Tags = []string{"aa", ""} }, "invalid field: tags.1 (required)"},
+		{func(u *validateUser) { u.Tags = []string{"aaa"} }, "invalid field: tags.0 (even)"},
+		{func(u *validateUser) { u.Addresses = append(u.Addresses, validateAddress{}) }, "invalid field: addresses.1.city (required)"},
+		{func(u *validateUser) { u.Meta["k"] = 10 }, "invalid field: meta.k (max=9)"},
+	}
+	for _, v := range cases {
+		u := validUser()
+		v.modify(&u)
+		err := validate(&u, r)
+		if err == nil || err.Error() != v.err {
+			t.Fatalf("want error %q got %v", v.err, err)
+		}
+	}
+
+	bad := []struct {
+		v   interface{}
+		err string
+	}{
+		{&struct {
+			N int `validate:"odd"`
+		}{}, `unknown validation rule "odd"`},
+		{&struct {
+			N uint `validate:"min=-1"`
+		}{}, "rule min=-1"},
+		{&struct {
+			S string `validate:"regexp=["`
+		}{}, "rule regexp=["},
+		{&struct {
+			S string `validate:"eqfield=T"`
+		}{}, "no field T for rule eqfield"},
+		{&struct {
+			N int `validate:"dive,min=1"`
+		}{}, "rule dive applied to int"},
+		{&struct {
+			A []struct {
+				N int `validate:"len=1.5"`
+			}
+		}{}, "rule len=1.5"},
+	}
+	for _, v := range bad {
+		err, ok := validate(v.v, r).(Error)
+		if !ok || err.Code != http.StatusInternalServerError || !strings.Contains(err.Detail, v.err) {
+			t.Fatalf("want error %q got %v", v.err, err)
+		}
+	}
+}
+
+func TestBindValidate(t *testing.T) {
+	type query struct {
+		Limit int    `query:"limit" validate:"min=1,max=100"`
+		Sort  string `query:"sort?" validate:"omitempty,lowercase"`
+	}
+
+	r := Default()
+	r.Rule("lowercase", func(v reflect.Value, _ string) bool {
+		return strings.ToLower(v.String()) == v.String()
+	})
+	r.GET("/", func(c *Ctx) error {
+		var q query
+		return c.Bind(&q)
+	})
+
+	cases := []struct {
+		query string
+		code  int
+	}{
+		{"limit=10", http.StatusOK},
+		{"limit=10&sort=name", http.StatusOK},
+		{"limit=0", http.StatusBadRequest},
+		{"limit=10&sort=Name", http.StatusBadRequest},
+	}
+	for _, v := range cases {
+		req, _ := http.NewRequest("GET", "/?"+v.query, nil)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != v.code {
+			t.Fatalf("[%s] bad status code, want %d got %d", v.query, v.code, rw.Code)
+		}
+	}
+
+	// rules registered after validating drop the compiled validations.
+	type tagged struct {
+		Tag string `query:"tag" validate:"uppercase"`
+	}
+	r.GET("/tagged", func(c *Ctx) error {
+		var q tagged
+		return c.Bind(&q)
+	})
+	for i, code := range []int{http.StatusInternalServerError, http.StatusOK} {
+		if i > 0 {
+			r.Rule("uppercase", func(v reflect.Value, _ string) bool {
+				return strings.ToUpper(v.String()) == v.String()
+			})
+		}
+		req, _ := http.NewRequest("GET", "/tagged?tag=A", nil)
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, req)
+		if rw.Code != code {
+			t.Fatalf("bad status code, want %d got %d %s", code, rw.Code, rw.Body)
+		}
+	}
+}